@@ -0,0 +1,628 @@
+package enroute
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/matthewmueller/enroute/ast"
+)
+
+// Analyze looks for routes that can never match, regexp slots that overlap
+// and routes that both match the same paths, where the winner depends on
+// precedence. Each problem comes with an example path that's checked against
+// the tree. Routes that require query parameters aren't analyzed.
+//
+// The analysis builds example paths from the routes, so it can miss problems
+// with slots whose values are hard to guess, like custom types.
+func (t *Tree[V]) Analyze() (problems []*Problem) {
+	problems = t.analyze("")
+	// Analyze the routes of each host on their own
+	if t.hosts != nil {
+		t.hosts.Each(func(n *Node[string]) bool {
+			if n.Label != "" {
+				host := sampleSegment(n.route.Sections[1:], nil)
+				problems = append(problems, t.trees[n.Label].analyze(host)...)
+			}
+			return true
+		})
+	}
+	return problems
+}
+
+// Problem with a route found by Analyze
+type Problem struct {
+	Kind    ProblemKind
+	Route   string // Route with the problem, or the route that wins
+	Other   string // Route that wins over Route, or the route that loses
+	Example string // Path that both routes match
+}
+
+// ProblemKind is the kind of problem found by Analyze
+type ProblemKind string
+
+const (
+	// Unreachable routes never match, since Other matches their paths first
+	Unreachable ProblemKind = "unreachable"
+	// Overlap is when the regexp slots of two routes match the same values.
+	// Route matches the example before Other.
+	Overlap ProblemKind = "overlap"
+	// Precedence is when both routes match the same paths and Route wins
+	// because it has a higher precedence
+	Precedence ProblemKind = "precedence"
+)
+
+func (p *Problem) String() string {
+	switch p.Kind {
+	case Unreachable:
+		return fmt.Sprintf("%q is unreachable, %q matches first (e.g. %s)", p.Route, p.Other, p.Example)
+	case Overlap:
+		return fmt.Sprintf("regexp slots of %q and %q overlap, %q matches first (e.g. %s)", p.Route, p.Other, p.Route, p.Example)
+	default:
+		return fmt.Sprintf("%q and %q both match %s, %q wins by precedence", p.Route, p.Other, p.Example, p.Route)
+	}
+}
+
+// analyzed is an expanded route that's being analyzed
+type analyzed struct {
+	label    string
+	segments []ast.Sections
+}
+
+// analyze the routes in the tree, prefixing examples with the host
+func (t *Tree[V]) analyze(host string) (problems []*Problem) {
+	if t.root == nil {
+		return nil
+	}
+	var routes []*analyzed
+	var labels []string
+	t.each(t.root, func(n *Node[V]) bool {
+		nodes := append(nodes[V]{n}, n.variants...)
+		for _, node := range nodes {
+			if node.route == nil || requiredQuery(node.route) > 0 {
+				continue
+			}
+			if !slices.Contains(labels, node.Label) {
+				labels = append(labels, node.Label)
+			}
+			routes = append(routes, &analyzed{node.Label, splitSections(node.route.Sections)})
+		}
+		return true
+	})
+	// Memoize the labels of the routes that match each example in match order
+	orders := map[string][]string{}
+	order := func(path string) []string {
+		if labels, ok := orders[path]; ok {
+			return labels
+		}
+		var labels []string
+		for _, match := range t.matchPaths(new(matcher), path) {
+			if !slices.Contains(labels, match.Route) {
+				labels = append(labels, match.Route)
+			}
+		}
+		orders[path] = labels
+		return labels
+	}
+	// Collect examples for each route and each pair of routes
+	examples := map[string][]string{}
+	type pair struct{ a, b *analyzed }
+	var pairs []pair
+	pairExamples := map[pair][]string{}
+	for i, a := range routes {
+		examples[a.label] = append(examples[a.label], jointPaths(a.segments, a.segments)...)
+		for _, b := range routes[i+1:] {
+			if a.label == b.label {
+				continue
+			}
+			// Paths that only one of the routes matches show it can be reached
+			examples[a.label] = append(examples[a.label], distinctPaths(a.segments, b.segments)...)
+			examples[b.label] = append(examples[b.label], distinctPaths(b.segments, a.segments)...)
+			paths := jointPaths(a.segments, b.segments)
+			if len(paths) == 0 {
+				continue
+			}
+			p := pair{a, b}
+			pairs = append(pairs, p)
+			pairExamples[p] = paths
+			examples[a.label] = append(examples[a.label], paths...)
+			examples[b.label] = append(examples[b.label], paths...)
+		}
+	}
+	// Routes are unreachable when they never come first for their examples
+	unreachable := map[string]bool{}
+	for _, label := range labels {
+		var first *Problem
+		reachable := false
+		for _, path := range examples[label] {
+			order := order(path)
+			if len(order) == 0 || !slices.Contains(order, label) {
+				continue
+			}
+			if order[0] == label {
+				reachable = true
+				break
+			}
+			if first == nil {
+				first = &Problem{Unreachable, label, order[0], host + path}
+			}
+		}
+		if !reachable && first != nil {
+			unreachable[label] = true
+			problems = append(problems, first)
+		}
+	}
+	// Report the routes that both match the same example
+	seen := map[[2]string]bool{}
+	for _, p := range pairs {
+		if unreachable[p.a.label] || unreachable[p.b.label] {
+			continue
+		}
+		key := [2]string{p.a.label, p.b.label}
+		if seen[key] {
+			continue
+		}
+		for _, path := range pairExamples[p] {
+			order := order(path)
+			ia, ib := slices.Index(order, p.a.label), slices.Index(order, p.b.label)
+			if ia < 0 || ib < 0 {
+				continue
+			}
+			seen[key] = true
+			problem := &Problem{Precedence, p.a.label, p.b.label, host + path}
+			if ib < ia {
+				problem.Route, problem.Other = p.b.label, p.a.label
+			}
+			if overlappingRegexps(p.a.segments, p.b.segments) {
+				problem.Kind = Overlap
+			}
+			problems = append(problems, problem)
+			break
+		}
+	}
+	return problems
+}
+
+// overlappingRegexps is true if both routes have a regexp slot in the same
+// segment
+func overlappingRegexps(a, b []ast.Sections) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if len(regexpSlots(a[i])) > 0 && len(regexpSlots(b[i])) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func regexpSlots(segment ast.Sections) (slots []*ast.RegexpSlot) {
+	for _, section := range segment {
+		if slot, ok := section.(*ast.RegexpSlot); ok {
+			slots = append(slots, slot)
+		}
+	}
+	return slots
+}
+
+// maxExamples is the number of example paths built for each pair of routes
+const maxExamples = 3
+
+// jointPaths builds example paths that both routes could match. The paths
+// still need to be checked against the tree.
+func jointPaths(a, b []ast.Sections) (paths []string) {
+	budget := 200
+	var walk func(a, b []ast.Sections, segments []string)
+	walk = func(a, b []ast.Sections, segments []string) {
+		budget--
+		if len(paths) >= maxExamples || budget < 0 {
+			return
+		}
+		if len(a) == 0 || len(b) == 0 {
+			if len(a) == 0 && len(b) == 0 {
+				paths = append(paths, "/"+strings.Join(segments, "/"))
+			}
+			return
+		}
+		for _, value := range jointValues(a[0], b[0]) {
+			next := append(slices.Clip(segments), value)
+			switch wa, wb := isWildcard(a[0]), isWildcard(b[0]); {
+			case wa && wb:
+				walk(nil, nil, next)
+			case wa:
+				// The wildcard absorbs the other route's segments
+				walk(b[1:], b[1:], next)
+			case wb:
+				walk(a[1:], a[1:], next)
+			default:
+				walk(a[1:], b[1:], next)
+			}
+		}
+	}
+	walk(a, b, nil)
+	return paths
+}
+
+// jointValues returns values for the segment that both route segments match
+func jointValues(a, b ast.Sections) (values []string) {
+	// Values that both regexps match help find overlapping regexp slots
+	var extra []string
+	for _, ra := range regexpSlots(a) {
+		for _, rb := range regexpSlots(b) {
+			extra = append(extra, regexpSamples(ra.Pattern.String(), rb.Pattern.String())...)
+		}
+	}
+	candidates := append(sampleSegments(a, extra), sampleSegments(b, extra)...)
+	for _, value := range candidates {
+		if slices.Contains(values, value) {
+			continue
+		}
+		if segmentMatches(a, value) && segmentMatches(b, value) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// distinctPaths builds example paths that route a matches, where the regexp
+// slots take values that the regexp slots of route b in the same segment
+// don't match. The paths still need to be checked against the tree.
+func distinctPaths(a, b []ast.Sections) (paths []string) {
+	for i, segment := range a {
+		if i >= len(b) {
+			break
+		}
+		var extra []string
+		for _, ra := range regexpSlots(segment) {
+			for _, rb := range regexpSlots(b[i]) {
+				extra = append(extra, regexpDifference(ra.Pattern.String(), rb.Pattern.String())...)
+			}
+		}
+		if len(extra) == 0 {
+			continue
+		}
+		for _, value := range sampleSegments(segment, extra) {
+			if len(paths) >= maxExamples {
+				return paths
+			}
+			if !segmentMatches(segment, value) || segmentMatches(b[i], value) {
+				continue
+			}
+			segments := make([]string, len(a))
+			for j := range a {
+				segments[j] = sampleSegment(a[j], nil)
+			}
+			segments[i] = value
+			paths = append(paths, "/"+strings.Join(segments, "/"))
+		}
+	}
+	return paths
+}
+
+// sampleSegment returns the first sample value of the segment
+func sampleSegment(segment ast.Sections, extra []string) string {
+	samples := sampleSegments(segment, extra)
+	if len(samples) == 0 {
+		return ""
+	}
+	return samples[0]
+}
+
+// maxSamples is the number of sample values for each segment
+const maxSamples = 6
+
+// sampleSegments returns sample values that the segment may match
+func sampleSegments(segment ast.Sections, extra []string) []string {
+	values := []string{""}
+	for _, section := range segment {
+		samples := sampleSection(section, extra)
+		next := make([]string, 0, maxSamples)
+		for _, value := range values {
+			for _, sample := range samples {
+				if len(next) < maxSamples {
+					next = append(next, value+sample)
+				}
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// typeSamples are sample values for the built-in slot types
+var typeSamples = map[string][]string{
+	"int":  {"1", "-1"},
+	"uuid": {"123e4567-e89b-12d3-a456-426614174000"},
+	"date": {"2024-01-01"},
+}
+
+// sampleSection returns sample values that the section may match
+func sampleSection(section ast.Section, extra []string) []string {
+	switch s := section.(type) {
+	case *ast.Path:
+		return []string{s.Value}
+	case *ast.Slash:
+		return []string{"/"}
+	case *ast.RegexpSlot:
+		pattern := s.Pattern.String()
+		return append(slices.Clone(extra), regexpSamples(pattern, pattern)...)
+	case *ast.TypedSlot:
+		var samples []string
+		for _, value := range append(append(extra, typeSamples[s.TypeName]...), "x", "1") {
+			if _, err := s.Type.Parse(value); err == nil {
+				samples = append(samples, value)
+			}
+		}
+		return samples
+	case *ast.WildcardSlot:
+		return append(slices.Clone(extra), "x", "x/y")
+	default:
+		return append(slices.Clone(extra), "x", "1")
+	}
+}
+
+// regexpSamples returns values that both patterns match, built from low and
+// high runes to tell apart ranges that only partly overlap
+func regexpSamples(a, b string) (samples []string) {
+	for _, high := range []bool{false, true} {
+		value, ok := regexpIntersection(a, b, high)
+		if ok && !slices.Contains(samples, value) {
+			samples = append(samples, value)
+		}
+	}
+	return samples
+}
+
+// regexpDifference returns values that pattern a matches and pattern b
+// doesn't
+func regexpDifference(a, b string) (samples []string) {
+	for _, high := range []bool{false, true} {
+		value, ok := regexpExclusion(a, b, high)
+		if ok && !slices.Contains(samples, value) {
+			samples = append(samples, value)
+		}
+	}
+	return samples
+}
+
+// maxExclusionStates bounds the states regexpExclusion visits
+const maxExclusionStates = 1000
+
+// regexpExclusion finds a short value that pattern a matches and pattern b
+// doesn't by walking a's program alongside every state b could be in
+func regexpExclusion(a, b string, high bool) (string, bool) {
+	pa, err := compileRegexp(a)
+	if err != nil {
+		return "", false
+	}
+	pb, err := compileRegexp(b)
+	if err != nil {
+		return "", false
+	}
+	type state struct {
+		a     uint32
+		b     []uint32
+		value string
+	}
+	seen := map[string]bool{}
+	var queue []state
+	push := func(as, bs []uint32, value string) {
+		slices.Sort(bs)
+		bs = slices.Compact(bs)
+		for _, a := range as {
+			key := fmt.Sprint(a, bs)
+			if !seen[key] {
+				seen[key] = true
+				queue = append(queue, state{a, bs, value})
+			}
+		}
+	}
+	push(progClosure(pa, uint32(pa.Start)), progClosure(pb, uint32(pb.Start)), "")
+	for len(queue) > 0 && len(seen) < maxExclusionStates {
+		s := queue[0]
+		queue = queue[1:]
+		ia := &pa.Inst[s.a]
+		if ia.Op == syntax.InstMatch {
+			if !slices.ContainsFunc(s.b, func(pc uint32) bool { return pb.Inst[pc].Op == syntax.InstMatch }) {
+				return s.value, true
+			}
+			continue
+		}
+		ranges := runeRanges(ia)
+		var others []rune
+		for _, pc := range s.b {
+			others = append(others, runeRanges(&pb.Inst[pc])...)
+		}
+		// Try a rune that both could take and a rune that leaves b behind
+		var runes []rune
+		if r, ok := commonRune(ranges, ranges, high); ok {
+			runes = append(runes, r)
+		}
+		if r, ok := exclusiveRune(ranges, others); ok {
+			runes = append(runes, r)
+		}
+		for _, r := range runes {
+			var next []uint32
+			for _, pc := range s.b {
+				if inst := &pb.Inst[pc]; inst.Op != syntax.InstMatch && inRanges(runeRanges(inst), r) {
+					next = append(next, progClosure(pb, inst.Out)...)
+				}
+			}
+			push(progClosure(pa, ia.Out), next, s.value+string(r))
+		}
+	}
+	return "", false
+}
+
+// exclusiveRune finds a rune in the ranges that isn't in the other ranges
+func exclusiveRune(ranges, others []rune) (rune, bool) {
+	for i := 0; i+1 < len(ranges); i += 2 {
+		lo, hi := max(ranges[i], '!'), ranges[i+1]
+		for r := lo; r <= hi && r < lo+128; r++ {
+			if isExampleRune(r) && !inRanges(others, r) {
+				return r, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// regexpIntersection finds the shortest value that both patterns match by
+// walking the product of their compiled programs
+func regexpIntersection(a, b string, high bool) (string, bool) {
+	pa, err := compileRegexp(a)
+	if err != nil {
+		return "", false
+	}
+	pb, err := compileRegexp(b)
+	if err != nil {
+		return "", false
+	}
+	type state struct{ a, b uint32 }
+	seen := map[state]bool{}
+	var queue []state
+	values := map[state]string{}
+	push := func(as, bs []uint32, value string) {
+		for _, a := range as {
+			for _, b := range bs {
+				s := state{a, b}
+				if !seen[s] {
+					seen[s] = true
+					values[s] = value
+					queue = append(queue, s)
+				}
+			}
+		}
+	}
+	push(progClosure(pa, uint32(pa.Start)), progClosure(pb, uint32(pb.Start)), "")
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		ia, ib := &pa.Inst[s.a], &pb.Inst[s.b]
+		if ia.Op == syntax.InstMatch && ib.Op == syntax.InstMatch {
+			return values[s], true
+		}
+		if ia.Op == syntax.InstMatch || ib.Op == syntax.InstMatch {
+			continue
+		}
+		r, ok := commonRune(runeRanges(ia), runeRanges(ib), high)
+		if !ok {
+			continue
+		}
+		push(progClosure(pa, ia.Out), progClosure(pb, ib.Out), values[s]+string(r))
+	}
+	return "", false
+}
+
+func compileRegexp(pattern string) (*syntax.Prog, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	return syntax.Compile(re.Simplify())
+}
+
+// progClosure follows the empty transitions from pc to the instructions that
+// consume a rune or match
+func progClosure(prog *syntax.Prog, pc uint32) (pcs []uint32) {
+	seen := map[uint32]bool{}
+	var visit func(pc uint32)
+	visit = func(pc uint32) {
+		if seen[pc] {
+			return
+		}
+		seen[pc] = true
+		inst := &prog.Inst[pc]
+		switch inst.Op {
+		case syntax.InstAlt, syntax.InstAltMatch:
+			visit(inst.Out)
+			visit(inst.Arg)
+		case syntax.InstCapture, syntax.InstNop, syntax.InstEmptyWidth:
+			visit(inst.Out)
+		case syntax.InstFail:
+		default:
+			pcs = append(pcs, pc)
+		}
+	}
+	visit(pc)
+	return pcs
+}
+
+// runeRanges returns the pairs of rune ranges the instruction consumes
+func runeRanges(inst *syntax.Inst) []rune {
+	switch inst.Op {
+	case syntax.InstRune1:
+		return []rune{inst.Rune[0], inst.Rune[0]}
+	case syntax.InstRuneAny:
+		return []rune{0, unicode.MaxRune}
+	case syntax.InstRuneAnyNotNL:
+		return []rune{0, '\n' - 1, '\n' + 1, unicode.MaxRune}
+	case syntax.InstRune:
+		if len(inst.Rune) == 1 {
+			ranges := []rune{inst.Rune[0], inst.Rune[0]}
+			if syntax.Flags(inst.Arg)&syntax.FoldCase != 0 {
+				for r := unicode.SimpleFold(inst.Rune[0]); r != inst.Rune[0]; r = unicode.SimpleFold(r) {
+					ranges = append(ranges, r, r)
+				}
+			}
+			return ranges
+		}
+		return inst.Rune
+	default:
+		return nil
+	}
+}
+
+// commonRune finds a rune in both ranges. Low runes prefer the runes that read
+// well in an example path, while high runes prefer the highest ASCII rune.
+func commonRune(a, b []rune, high bool) (rune, bool) {
+	if !high {
+		for _, r := range "abcxyz0123456789ABCXYZ-_." {
+			if inRanges(a, r) && inRanges(b, r) {
+				return r, true
+			}
+		}
+	}
+	found, ok := rune(0), false
+	for i := 0; i+1 < len(a); i += 2 {
+		for j := 0; j+1 < len(b); j += 2 {
+			lo, hi := max(a[i], b[j], '!'), min(a[i+1], b[j+1])
+			if high {
+				// Walk down from the highest ASCII rune in the range
+				for r := min(hi, '~'); r >= lo && r > min(hi, '~')-128; r-- {
+					if isExampleRune(r) && (!ok || r > found) {
+						found, ok = r, true
+						break
+					}
+				}
+				continue
+			}
+			// Walk up from the lowest printable rune in the range
+			for r := lo; r <= hi && r < lo+128; r++ {
+				if isExampleRune(r) {
+					return r, true
+				}
+			}
+		}
+	}
+	if !ok && high {
+		return commonRune(a, b, false)
+	}
+	return found, ok
+}
+
+// inRanges is true if the rune is in one of the pairs of rune ranges
+func inRanges(ranges []rune, r rune) bool {
+	for i := 0; i+1 < len(ranges); i += 2 {
+		if ranges[i] <= r && r <= ranges[i+1] {
+			return true
+		}
+	}
+	return false
+}
+
+// isExampleRune is true if the rune can be used in an example path
+func isExampleRune(r rune) bool {
+	return r != '/' && r != '?' && r != '%' && r != '#' && utf8.ValidRune(r) && unicode.IsPrint(r)
+}
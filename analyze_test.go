@@ -0,0 +1,94 @@
+package enroute_test
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/matthewmueller/diff"
+	"github.com/matthewmueller/enroute"
+)
+
+func analyze(tree *enroute.Tree[string]) string {
+	out := ""
+	for _, problem := range tree.Analyze() {
+		out += problem.String() + "\n"
+	}
+	return out
+}
+
+func TestAnalyze(t *testing.T) {
+	tree := enroute.New()
+	tree.MustInsert("/users/new", "new")
+	tree.MustInsert("/users/{id}", "show")
+	tree.MustInsert("/users/{id}/edit", "edit")
+	tree.MustInsert("/files/{path*}", "files")
+	tree.MustInsert("/about", "about")
+	diff.TestString(t, `
+"/users/new" and "/users/{id}" both match /users/new, "/users/new" wins by precedence
+`[1:], analyze(tree))
+}
+
+func TestAnalyzeUnreachable(t *testing.T) {
+	tree := enroute.New()
+	tree.MustInsert("/posts/{id|[0-9]+}", "digits")
+	tree.MustInsert("/posts/{id|[0-5]+}", "low")
+	tree.MustInsert("/posts/{slug}", "slug")
+	diff.TestString(t, `
+"/posts/{id|^[0-5]+$}" is unreachable, "/posts/{id|^[0-9]+$}" matches first (e.g. /posts/0)
+"/posts/{id|^[0-9]+$}" and "/posts/{slug}" both match /posts/0, "/posts/{id|^[0-9]+$}" wins by precedence
+`[1:], analyze(tree))
+}
+
+func TestAnalyzeOverlap(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	tree.MustInsert("/tags/{name|[a-c]+}", "low")
+	tree.MustInsert("/tags/{name|[b-d]+}", "high")
+	tree.MustInsert("/codes/{code|[a-z]{2}}", "letters")
+	tree.MustInsert("/codes/{code|[0-9]{2}}", "digits")
+	problems := tree.Analyze()
+	is.Equal(len(problems), 1)
+	is.Equal(problems[0].Kind, enroute.Overlap)
+	is.Equal(problems[0].Route, "/tags/{name|^[a-c]+$}")
+	is.Equal(problems[0].Other, "/tags/{name|^[b-d]+$}")
+	is.Equal(problems[0].Example, "/tags/b")
+}
+
+func TestAnalyzeOptional(t *testing.T) {
+	tree := enroute.New()
+	tree.MustInsert("/{page}", "page")
+	tree.MustInsert("/docs/{path*}", "docs")
+	tree.MustInsert("/blog/{slug?}", "blog")
+	tree.MustInsert("/blog", "index")
+	diff.TestString(t, `
+"/docs/{path*}" and "/{page}" both match /docs, "/docs/{path*}" wins by precedence
+"/blog" and "/{page}" both match /blog, "/blog" wins by precedence
+`[1:], analyze(tree))
+}
+
+func TestAnalyzeHost(t *testing.T) {
+	tree := enroute.New()
+	tree.MustInsert("{tenant}.example.com/{page}", "page")
+	tree.MustInsert("{tenant}.example.com/about", "about")
+	tree.MustInsert("/about", "about")
+	tree.MustInsert("/search?q={query}", "search")
+	diff.TestString(t, `
+"{tenant}.example.com/about" and "{tenant}.example.com/{page}" both match x.example.com/about, "{tenant}.example.com/about" wins by precedence
+`[1:], analyze(tree))
+	diff.TestString(t, ``, analyze(enroute.New()))
+}
+
+func TestAnalyzePartialOverlap(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	tree.MustInsert("/a/{x|[a-z]+}", "x")
+	tree.MustInsert("/a/{y|[a-c0-9]+}", "y")
+	match, err := tree.Match("/a/0")
+	is.NoErr(err)
+	is.Equal(match.Value, "y")
+	problems := tree.Analyze()
+	is.Equal(len(problems), 1)
+	is.Equal(problems[0].Kind, enroute.Overlap)
+	is.Equal(problems[0].Route, "/a/{x|^[a-z]+$}")
+	is.Equal(problems[0].Other, "/a/{y|^[a-c0-9]+$}")
+}
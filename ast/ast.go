@@ -1,9 +1,14 @@
 package ast
 
 import (
+	"fmt"
+	"maps"
+	"net/url"
 	"regexp"
 	"slices"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Node interface {
@@ -18,19 +23,33 @@ var (
 	_ Node = (*OptionalSlot)(nil)
 	_ Node = (*WildcardSlot)(nil)
 	_ Node = (*RegexpSlot)(nil)
+	_ Node = (*TypedSlot)(nil)
 )
 
 type Routes []Route
 
 type Route struct {
+	Host     Sections // Host sections, empty for routes without a host
 	Sections Sections
+	Query    []*Query // Query parameters, empty for routes without a query
 }
 
 func (r *Route) String() string {
 	s := new(strings.Builder)
+	for _, section := range r.Host {
+		s.WriteString(section.String())
+	}
 	for _, section := range r.Sections {
 		s.WriteString(section.String())
 	}
+	for i, query := range r.Query {
+		if i == 0 {
+			s.WriteString("?")
+		} else {
+			s.WriteString("&")
+		}
+		s.WriteString(query.String())
+	}
 	return s.String()
 }
 
@@ -59,14 +78,18 @@ func trimRightSlash(r *Route) *Route {
 func (r *Route) Expand() (routes []*Route) {
 	// Clone the route
 	route := &Route{
+		Host:     r.Host,
 		Sections: slices.Clone(r.Sections),
+		Query:    r.Query,
 	}
 	for i, section := range route.Sections {
 		switch s := section.(type) {
 		case *OptionalSlot:
 			// Create route before the optional slot
 			routes = append(routes, trimRightSlash(&Route{
+				Host:     r.Host,
 				Sections: route.Sections[:i],
+				Query:    r.Query,
 			}))
 			// Create a new route with the slot required
 			route.Sections[i] = &RequiredSlot{
@@ -76,7 +99,9 @@ func (r *Route) Expand() (routes []*Route) {
 		case *WildcardSlot:
 			// Create route before the wildcard slot
 			routes = append(routes, trimRightSlash(&Route{
+				Host:     r.Host,
 				Sections: route.Sections[:i],
+				Query:    r.Query,
 			}))
 		}
 	}
@@ -84,6 +109,195 @@ func (r *Route) Expand() (routes []*Route) {
 	return routes
 }
 
+// Build a path from the route by filling its slots with values. Optional and
+// wildcard slots without a value are dropped from the path. Routes with a host
+// are built with the host in front of the path (e.g. acme.example.com/). The
+// values are escaped, so they match back as the same values.
+func (r *Route) Build(values map[string]string) (string, error) {
+	return r.build(values, url.PathEscape)
+}
+
+// BuildEscaped is like Build, but the values are already escaped, so they're
+// written into the path as they are
+func (r *Route) BuildEscaped(values map[string]string) (string, error) {
+	return r.build(values, func(value string) string { return value })
+}
+
+func (r *Route) build(values map[string]string, escape func(string) string) (string, error) {
+	host := new(strings.Builder)
+	if err := build(host, r.Host, values, func(value string) string { return value }); err != nil {
+		return "", err
+	}
+	s := new(strings.Builder)
+	if err := build(s, r.Sections, values, escape); err != nil {
+		return "", err
+	}
+	path := strings.TrimRight(s.String(), "/")
+	if path == "" {
+		path = "/"
+	}
+	query, err := buildQuery(r.Query, values)
+	if err != nil {
+		return "", err
+	}
+	return host.String() + path + query, nil
+}
+
+// buildQuery builds the query string by filling its slots with values.
+// Optional slots without a value are dropped from the query.
+func buildQuery(query []*Query, values map[string]string) (string, error) {
+	params := make([]string, 0, len(query))
+	for _, q := range query {
+		key := url.QueryEscape(q.Key)
+		switch v := q.Value.(type) {
+		case nil:
+			params = append(params, key)
+		case *Path:
+			params = append(params, key+"="+url.QueryEscape(v.Value))
+		case Slot:
+			value := values[v.Slot()]
+			if value == "" {
+				if _, ok := v.(*OptionalSlot); ok {
+					continue
+				}
+				return "", &ErrMissingSlot{v.Slot()}
+			} else if !q.Match(value, true) {
+				return "", &ErrInvalidSlot{v.Slot(), value}
+			}
+			params = append(params, key+"="+url.QueryEscape(value))
+		}
+	}
+	if len(params) == 0 {
+		return "", nil
+	}
+	return "?" + strings.Join(params, "&"), nil
+}
+
+// build the sections into s by filling its slots with the escaped values
+func build(s *strings.Builder, sections Sections, values map[string]string, escape func(string) string) error {
+	for _, section := range sections {
+		switch sec := section.(type) {
+		case *RequiredSlot:
+			value := values[sec.Key]
+			escaped := escape(value)
+			if value == "" {
+				return &ErrMissingSlot{sec.Key}
+			} else if hasDelimiter(sec.Delimiters, escaped) {
+				return &ErrInvalidSlot{sec.Key, value}
+			}
+			s.WriteString(escaped)
+		case *RegexpSlot:
+			value := values[sec.Key]
+			escaped := escape(value)
+			if value == "" {
+				return &ErrMissingSlot{sec.Key}
+			} else if hasDelimiter(sec.Delimiters, escaped) || !sec.Pattern.MatchString(value) {
+				return &ErrInvalidSlot{sec.Key, value}
+			}
+			s.WriteString(escaped)
+		case *TypedSlot:
+			value := values[sec.Key]
+			escaped := escape(value)
+			if value == "" {
+				return &ErrMissingSlot{sec.Key}
+			} else if hasDelimiter(sec.Delimiters, escaped) {
+				return &ErrInvalidSlot{sec.Key, value}
+			} else if _, err := sec.Type.Parse(value); err != nil {
+				return &ErrInvalidSlot{sec.Key, value}
+			}
+			s.WriteString(escaped)
+		case *OptionalSlot:
+			value := values[sec.Key]
+			escaped := escape(value)
+			if value == "" {
+				continue
+			} else if hasDelimiter(sec.Delimiters, escaped) {
+				return &ErrInvalidSlot{sec.Key, value}
+			}
+			s.WriteString(escaped)
+		case *WildcardSlot:
+			// Wildcards join the segments of the value with slashes
+			segments := strings.Split(strings.Trim(values[sec.Key], "/"), "/")
+			for i, segment := range segments {
+				segments[i] = escape(segment)
+			}
+			s.WriteString(strings.Join(segments, "/"))
+		default:
+			s.WriteString(section.String())
+		}
+	}
+	return nil
+}
+
+// Query is a query parameter of a route like q={query}
+type Query struct {
+	Key   string
+	Value Section // Fixed value, slot or nil if only the key must be present
+}
+
+func (q *Query) String() string {
+	if q.Value == nil {
+		return q.Key
+	}
+	return q.Key + "=" + q.Value.String()
+}
+
+// Required is true if the parameter must be in the query to match
+func (q *Query) Required() bool {
+	_, ok := q.Value.(*OptionalSlot)
+	return !ok
+}
+
+// Match the parameter against the value from the query. Present is false when
+// the query doesn't have the parameter.
+func (q *Query) Match(value string, present bool) bool {
+	switch v := q.Value.(type) {
+	case nil:
+		return present
+	case *OptionalSlot:
+		return true
+	case *Path:
+		return present && value == v.Value
+	case *RequiredSlot:
+		return present && value != ""
+	case *RegexpSlot:
+		return present && v.Pattern.MatchString(value)
+	case *TypedSlot:
+		if !present {
+			return false
+		}
+		_, err := v.Type.Parse(value)
+		return err == nil
+	default:
+		return false
+	}
+}
+
+func hasDelimiter(delimiters map[rune]bool, value string) bool {
+	return delimiterAt(delimiters, value) < len(value)
+}
+
+// ErrMissingSlot is returned when building a route without a value for a
+// required slot
+type ErrMissingSlot struct {
+	Slot string
+}
+
+func (e *ErrMissingSlot) Error() string {
+	return fmt.Sprintf("missing value for slot %q", e.Slot)
+}
+
+// ErrInvalidSlot is returned when building a route with a value that the slot
+// wouldn't match
+type ErrInvalidSlot struct {
+	Slot  string
+	Value string
+}
+
+func (e *ErrInvalidSlot) Error() string {
+	return fmt.Sprintf("invalid value %q for slot %q", e.Value, e.Slot)
+}
+
 // Section of the route
 type Section interface {
 	Node
@@ -132,6 +346,11 @@ func (sections Sections) At(n int) string {
 				return "{slot|" + s.Pattern.String() + "}"
 			}
 			n--
+		case *TypedSlot:
+			if n == 0 {
+				return "{slot:" + s.TypeName + "}"
+			}
+			n--
 		}
 	}
 	return ""
@@ -172,13 +391,13 @@ func (sections Sections) Split(at int) []Sections {
 					return []Sections{sections}
 				}
 				// Split the path in two
-				leftPath := &Path{Value: left}
-				rightPath := &Path{Value: right}
+				leftPath := &Path{Value: left, CaseSensitive: s.CaseSensitive}
+				rightPath := &Path{Value: right, CaseSensitive: s.CaseSensitive}
 				leftSections := append(slices.Clone(sections[:i]), leftPath)
 				rightSections := append(append(Sections{}, rightPath), sections[i+1:]...)
 				return []Sections{leftSections, rightSections}
 			}
-		case *RequiredSlot, *OptionalSlot, *WildcardSlot, *RegexpSlot:
+		case *RequiredSlot, *OptionalSlot, *WildcardSlot, *RegexpSlot, *TypedSlot:
 			if at != 0 {
 				at--
 				continue
@@ -192,6 +411,62 @@ func (sections Sections) Split(at int) []Sections {
 	return []Sections{sections}
 }
 
+// Clone the sections deeply, so changes to the slot delimiters while inserting
+// routes don't affect the original sections.
+func (sections Sections) Clone() Sections {
+	clone := make(Sections, len(sections))
+	for i, section := range sections {
+		switch s := section.(type) {
+		case *Slash:
+			c := *s
+			clone[i] = &c
+		case *Path:
+			c := *s
+			clone[i] = &c
+		case *RequiredSlot:
+			c := *s
+			c.Delimiters = maps.Clone(s.Delimiters)
+			clone[i] = &c
+		case *OptionalSlot:
+			c := *s
+			c.Delimiters = maps.Clone(s.Delimiters)
+			clone[i] = &c
+		case *WildcardSlot:
+			c := *s
+			c.Delimiters = maps.Clone(s.Delimiters)
+			clone[i] = &c
+		case *RegexpSlot:
+			c := *s
+			c.Delimiters = maps.Clone(s.Delimiters)
+			clone[i] = &c
+		case *TypedSlot:
+			c := *s
+			c.Delimiters = maps.Clone(s.Delimiters)
+			clone[i] = &c
+		default:
+			clone[i] = section
+		}
+	}
+	return clone
+}
+
+// Join the sections together, merging paths that meet at the boundary
+func (sections Sections) Join(secs Sections) Sections {
+	joined := slices.Clone(sections)
+	if len(joined) > 0 && len(secs) > 0 {
+		left, ok := joined[len(joined)-1].(*Path)
+		right, ok2 := secs[0].(*Path)
+		if ok && ok2 {
+			joined[len(joined)-1] = &Path{
+				Value:         left.Value + right.Value,
+				CaseSensitive: left.CaseSensitive,
+			}
+			secs = secs[1:]
+		}
+	}
+	return append(joined, secs...)
+}
+
 func (sections Sections) String() string {
 	s := new(strings.Builder)
 	for _, section := range sections {
@@ -206,6 +481,7 @@ var (
 	_ Section = (*OptionalSlot)(nil)
 	_ Section = (*WildcardSlot)(nil)
 	_ Section = (*RegexpSlot)(nil)
+	_ Section = (*TypedSlot)(nil)
 )
 
 type Slash struct {
@@ -248,7 +524,8 @@ func (p *Slash) Priority() int {
 }
 
 type Path struct {
-	Value string
+	Value         string
+	CaseSensitive bool // Match the path's casing exactly
 }
 
 func (p *Path) String() string {
@@ -266,8 +543,9 @@ func (p *Path) Compare(sec Section) (index int, equal bool) {
 	l1 := len(r1)
 	l2 := len(r2)
 	max := min(l1, l2)
+	fold := !p.CaseSensitive || !p2.CaseSensitive
 	for i := 0; i < max; i++ {
-		if r1[i] != r2[i] {
+		if r1[i] != r2[i] && !(fold && unicode.ToLower(r1[i]) == unicode.ToLower(r2[i])) {
 			return index, false
 		}
 		index++
@@ -275,20 +553,72 @@ func (p *Path) Compare(sec Section) (index int, equal bool) {
 	return index, l1 == l2
 }
 
+// Len is the number of runes in the path
 func (p *Path) Len() int {
-	return len(p.Value)
+	return utf8.RuneCountInString(p.Value)
 }
 
+// Match the path against the input, decoding any percent-encoded characters
+// in the input before comparing
 func (p *Path) Match(path string) (index int, slots []string) {
-	valueLen := p.Len()
-	if len(path) < valueLen {
+	prefix, width, ok := decodePrefix(path, len(p.Value))
+	if !ok {
 		return index, slots
 	}
-	prefix := strings.ToLower(path[:valueLen])
-	if prefix != p.Value {
+	if p.CaseSensitive && prefix != p.Value {
+		return index, slots
+	} else if !strings.EqualFold(prefix, p.Value) {
 		return index, slots
 	}
-	return valueLen, slots
+	return width, slots
+}
+
+// decodePrefix decodes the start of the path until it's n bytes long. It
+// returns the decoded prefix and how much of the path it took.
+func decodePrefix(path string, n int) (prefix string, width int, ok bool) {
+	if len(path) < n {
+		return "", 0, false
+	}
+	// Fast path for prefixes without any percent-encoding
+	if strings.IndexByte(path[:n], '%') < 0 {
+		return path[:n], n, true
+	}
+	decoded := make([]byte, 0, n)
+	for len(decoded) < n {
+		if width >= len(path) {
+			return "", 0, false
+		}
+		if path[width] != '%' {
+			decoded = append(decoded, path[width])
+			width++
+			continue
+		}
+		if width+2 >= len(path) || !isHex(path[width+1]) || !isHex(path[width+2]) {
+			return "", 0, false
+		}
+		decoded = append(decoded, unhex(path[width+1])<<4|unhex(path[width+2]))
+		width += 3
+	}
+	return string(decoded), width, true
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+// Unescape decodes the percent-encoded characters in a slot's value
+func Unescape(value string) (string, error) {
+	if strings.IndexByte(value, '%') < 0 {
+		return value, nil
+	}
+	return url.PathUnescape(value)
 }
 
 func (p *Path) Priority() int {
@@ -299,7 +629,7 @@ type Slot interface {
 	Node
 	Section
 	Slot() string
-	delimiters() map[byte]bool
+	delimiters() map[rune]bool
 }
 
 var (
@@ -307,14 +637,15 @@ var (
 	_ Slot = (*OptionalSlot)(nil)
 	_ Slot = (*WildcardSlot)(nil)
 	_ Slot = (*RegexpSlot)(nil)
+	_ Slot = (*TypedSlot)(nil)
 )
 
 type RequiredSlot struct {
 	Key        string
-	Delimiters map[byte]bool
+	Delimiters map[rune]bool
 }
 
-func (s *RequiredSlot) delimiters() map[byte]bool {
+func (s *RequiredSlot) delimiters() map[rune]bool {
 	return s.Delimiters
 }
 
@@ -328,6 +659,8 @@ func (s *RequiredSlot) Compare(sec Section) (index int, equal bool) {
 		return index, false
 	} else if _, ok := s2.(*RegexpSlot); ok {
 		return index, false
+	} else if _, ok := s2.(*TypedSlot); ok {
+		return index, false
 	}
 	// Merge the delimiter list
 	for k := range s2.delimiters() {
@@ -352,13 +685,7 @@ func (s *RequiredSlot) String() string {
 }
 
 func (s *RequiredSlot) Match(path string) (index int, slots []string) {
-	lpath := len(path)
-	for i := 0; i < lpath; i++ {
-		if s.Delimiters[path[i]] {
-			break
-		}
-		index++
-	}
+	index = delimiterAt(s.Delimiters, path)
 	if index == 0 {
 		return index, slots
 	}
@@ -372,10 +699,10 @@ func (p *RequiredSlot) Priority() int {
 
 type OptionalSlot struct {
 	Key        string
-	Delimiters map[byte]bool
+	Delimiters map[rune]bool
 }
 
-func (s *OptionalSlot) delimiters() map[byte]bool {
+func (s *OptionalSlot) delimiters() map[rune]bool {
 	return s.Delimiters
 }
 
@@ -420,10 +747,10 @@ func (p *OptionalSlot) Priority() int {
 
 type WildcardSlot struct {
 	Key        string
-	Delimiters map[byte]bool
+	Delimiters map[rune]bool
 }
 
-func (s *WildcardSlot) delimiters() map[byte]bool {
+func (s *WildcardSlot) delimiters() map[rune]bool {
 	return s.Delimiters
 }
 
@@ -441,6 +768,8 @@ func (s *WildcardSlot) Compare(sec Section) (index int, equal bool) {
 		return index, false
 	} else if _, ok := s2.(*RegexpSlot); ok {
 		return index, false
+	} else if _, ok := s2.(*TypedSlot); ok {
+		return index, false
 	}
 	// Merge the delimiter list
 	for k := range s2.delimiters() {
@@ -472,10 +801,10 @@ func (p *WildcardSlot) Priority() int {
 type RegexpSlot struct {
 	Key        string
 	Pattern    *regexp.Regexp
-	Delimiters map[byte]bool
+	Delimiters map[rune]bool
 }
 
-func (s *RegexpSlot) delimiters() map[byte]bool {
+func (s *RegexpSlot) delimiters() map[rune]bool {
 	return s.Delimiters
 }
 
@@ -515,7 +844,8 @@ func (r *RegexpSlot) String() string {
 func (s *RegexpSlot) Match(path string) (index int, slots []string) {
 	i := delimiterAt(s.Delimiters, path)
 	prefix := path[:i]
-	if !s.Pattern.MatchString(prefix) {
+	value, err := Unescape(prefix)
+	if err != nil || !s.Pattern.MatchString(value) {
 		return 0, slots
 	}
 	slots = append(slots, prefix)
@@ -526,14 +856,91 @@ func (p *RegexpSlot) Priority() int {
 	return 1
 }
 
-func delimiterAt(delimiters map[byte]bool, path string) int {
-	index := 0
-	lpath := len(path)
-	for i := 0; i < lpath; i++ {
-		if delimiters[path[i]] {
-			break
+// TypedSlot is a slot with a named type like {id:int} that checks and converts
+// the slot's value
+type TypedSlot struct {
+	Key        string
+	TypeName   string
+	Type       Type
+	Delimiters map[rune]bool
+}
+
+func (s *TypedSlot) delimiters() map[rune]bool {
+	return s.Delimiters
+}
+
+func (s *TypedSlot) Len() int {
+	return 1
+}
+
+// Compare a slot to another section
+// Note: this can modify the slot's delimiters. I couldn't find a better spot
+// for this logic.
+func (s *TypedSlot) Compare(sec Section) (index int, equal bool) {
+	index = -1
+	s2, ok := sec.(*TypedSlot)
+	if !ok {
+		return index, false
+	} else if s.TypeName != s2.TypeName {
+		return index, false
+	}
+	// Merge the delimiter list
+	for k := range s2.delimiters() {
+		s.Delimiters[k] = true
+	}
+	// Different keys don't matter for comparison
+	// and slots count as one character.
+	index++
+	return index, true
+}
+
+func (s *TypedSlot) Slot() string {
+	return s.Key
+}
+
+func (s *TypedSlot) String() string {
+	return "{" + s.Key + ":" + s.TypeName + "}"
+}
+
+func (s *TypedSlot) Match(path string) (index int, slots []string) {
+	index, raw, _ := s.MatchParsed(path)
+	if index == 0 {
+		return 0, slots
+	}
+	slots = append(slots, raw)
+	return index, slots
+}
+
+// MatchParsed matches the slot like Match, also returning the slot's raw value
+// and the value it parsed to
+func (s *TypedSlot) MatchParsed(path string) (index int, raw string, parsed any) {
+	i := delimiterAt(s.Delimiters, path)
+	if i == 0 {
+		return 0, "", nil
+	}
+	raw = path[:i]
+	value, err := Unescape(raw)
+	if err != nil {
+		return 0, "", nil
+	}
+	parsed, err = s.Type.Parse(value)
+	if err != nil {
+		return 0, "", nil
+	}
+	return i, raw, parsed
+}
+
+func (p *TypedSlot) Priority() int {
+	return 1
+}
+
+// delimiterAt returns the byte index of the first delimiter in the path or the
+// length of the path if there isn't one
+func delimiterAt(delimiters map[rune]bool, path string) int {
+	for i, r := range path {
+		if delimiters[r] {
+			return i
 		}
-		index++
 	}
-	return index
+	return len(path)
 }
@@ -1,9 +1,11 @@
 package ast_test
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/matryer/is"
+	"github.com/matthewmueller/enroute/ast"
 	"github.com/matthewmueller/enroute/internal/parser"
 )
 
@@ -32,6 +34,33 @@ func TestSplitCopy(t *testing.T) {
 	is.Equal(parts[1].String(), "er")
 }
 
+func TestJoin(t *testing.T) {
+	is := is.New(t)
+	route, err := parser.Parse("/slower/{name}")
+	is.NoErr(err)
+	parts := route.Sections.Split(5)
+	is.Equal(parts[0].String(), "/slow")
+	is.Equal(parts[1].String(), "er/{name}")
+	joined := parts[0].Join(parts[1])
+	is.Equal(joined.String(), "/slower/{name}")
+	is.Equal(len(joined), len(route.Sections))
+	is.Equal(route.String(), "/slower/{name}")
+}
+
+func TestClone(t *testing.T) {
+	is := is.New(t)
+	route, err := parser.Parse("/{from}-{to}/{id|[0-9]+}")
+	is.NoErr(err)
+	clone := route.Sections.Clone()
+	is.Equal(clone.String(), route.Sections.String())
+	other, err := parser.Parse("/{from}.{to}")
+	is.NoErr(err)
+	is.Equal(clone.LongestCommonPrefix(other.Sections), 2)
+	// Comparing merges delimiters into the clone, but not the original
+	is.True(clone[1].(*ast.RequiredSlot).Delimiters['.'])
+	is.True(!route.Sections[1].(*ast.RequiredSlot).Delimiters['.'])
+}
+
 func expandEqual(t *testing.T, route string, expects ...string) {
 	t.Helper()
 	t.Run(route, func(t *testing.T) {
@@ -91,3 +120,67 @@ func TestLCP(t *testing.T) {
 	equalLCP(t, "/{a}", "/{b}", 2)
 	equalLCP(t, "/x{number}", "/x-{custom}", 2)
 }
+
+func buildEqual(t *testing.T, route string, values map[string]string, expect string) {
+	t.Helper()
+	t.Run(route, func(t *testing.T) {
+		t.Helper()
+		r, err := parser.Parse(route)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path, err := r.Build(values)
+		if err != nil {
+			if err.Error() == expect {
+				return
+			}
+			t.Fatal(err)
+		}
+		if path != expect {
+			t.Fatalf("expected %q, got %q", expect, path)
+		}
+	})
+}
+
+func TestBuild(t *testing.T) {
+	buildEqual(t, "/", nil, "/")
+	buildEqual(t, "/about", nil, "/about")
+	buildEqual(t, "/about/", nil, "/about")
+	buildEqual(t, "/users/{id}", map[string]string{"id": "10"}, "/users/10")
+	buildEqual(t, "/users/{id}", map[string]string{}, `missing value for slot "id"`)
+	buildEqual(t, "/users/{id}", map[string]string{"id": "a/b"}, "/users/a%2Fb")
+	buildEqual(t, "/users/{id}/edit", map[string]string{"id": "10", "other": "20"}, "/users/10/edit")
+	buildEqual(t, "/users/{id}", map[string]string{"id": "a?b c%"}, "/users/a%3Fb%20c%25")
+	buildEqual(t, "/{path*}", map[string]string{"path": "a b/c?d"}, "/a%20b/c%3Fd")
+	buildEqual(t, "/fly/{from}-{to}", map[string]string{"from": "sfo", "to": "ber"}, "/fly/sfo-ber")
+	buildEqual(t, "/fly/{from}-{to}", map[string]string{"from": "s-fo", "to": "ber"}, `invalid value "s-fo" for slot "from"`)
+	buildEqual(t, "/{id?}", nil, "/")
+	buildEqual(t, "/{id?}", map[string]string{"id": "10"}, "/10")
+	buildEqual(t, "/users/{id?}", nil, "/users")
+	buildEqual(t, "/users/{id}.{format?}", map[string]string{"id": "10"}, "/users/10.")
+	buildEqual(t, "/users/{id}.{format?}", map[string]string{"id": "10", "format": "json"}, "/users/10.json")
+	buildEqual(t, "/{path*}", nil, "/")
+	buildEqual(t, "/{path*}", map[string]string{"path": "a/b/c"}, "/a/b/c")
+	buildEqual(t, "/{owner}/{repo}/{path*}", map[string]string{"owner": "a", "repo": "b", "path": "/c/d/"}, "/a/b/c/d")
+	buildEqual(t, "/v{major|[0-9]+}.{minor|[0-9]+}", map[string]string{"major": "1", "minor": "20"}, "/v1.20")
+	buildEqual(t, "/v{major|[0-9]+}.{minor|[0-9]+}", map[string]string{"major": "1"}, `missing value for slot "minor"`)
+	buildEqual(t, "/v{major|[0-9]+}.{minor|[0-9]+}", map[string]string{"major": "1", "minor": "x"}, `invalid value "x" for slot "minor"`)
+	buildEqual(t, "/users/{id:int}", map[string]string{"id": "10"}, "/users/10")
+	buildEqual(t, "/users/{id:int}", map[string]string{"id": "ten"}, `invalid value "ten" for slot "id"`)
+	buildEqual(t, "/users/{id:int}", nil, `missing value for slot "id"`)
+}
+
+func TestBuildErrors(t *testing.T) {
+	is := is.New(t)
+	route, err := parser.Parse("/users/{id|[0-9]+}")
+	is.NoErr(err)
+	_, err = route.Build(nil)
+	missing := new(ast.ErrMissingSlot)
+	is.True(errors.As(err, &missing))
+	is.Equal(missing.Slot, "id")
+	_, err = route.Build(map[string]string{"id": "abc"})
+	invalid := new(ast.ErrInvalidSlot)
+	is.True(errors.As(err, &invalid))
+	is.Equal(invalid.Slot, "id")
+	is.Equal(invalid.Value, "abc")
+}
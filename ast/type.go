@@ -0,0 +1,82 @@
+package ast
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Type checks and converts the values of typed slots like {id:int}
+type Type interface {
+	Parse(value string) (any, error)
+}
+
+// TypeFunc adapts a function into a slot type
+type TypeFunc func(value string) (any, error)
+
+// Parse the value
+func (fn TypeFunc) Parse(value string) (any, error) {
+	return fn(value)
+}
+
+var (
+	typesMu sync.RWMutex
+	types   = map[string]Type{
+		"int":  TypeFunc(parseInt),
+		"uuid": TypeFunc(parseUUID),
+		"date": TypeFunc(parseDate),
+	}
+)
+
+// RegisterType registers a slot type under a name, replacing any existing type
+// with the same name. Types must be registered before parsing routes that use
+// them.
+func RegisterType(name string, t Type) {
+	typesMu.Lock()
+	defer typesMu.Unlock()
+	types[name] = t
+}
+
+// LookupType finds a slot type by name
+func LookupType(name string) (Type, bool) {
+	typesMu.RLock()
+	defer typesMu.RUnlock()
+	t, ok := types[name]
+	return t, ok
+}
+
+// parseInt parses a base 10 integer
+func parseInt(value string) (any, error) {
+	return strconv.Atoi(value)
+}
+
+// parseUUID checks that the value is a UUID in its canonical form (e.g.
+// 123e4567-e89b-12d3-a456-426614174000)
+func parseUUID(value string) (any, error) {
+	if len(value) != 36 {
+		return nil, fmt.Errorf("invalid uuid %q", value)
+	}
+	for i := 0; i < len(value); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if value[i] != '-' {
+				return nil, fmt.Errorf("invalid uuid %q", value)
+			}
+		default:
+			if !isHex(value[i]) {
+				return nil, fmt.Errorf("invalid uuid %q", value)
+			}
+		}
+	}
+	return value, nil
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// parseDate parses a date in the YYYY-MM-DD format
+func parseDate(value string) (any, error) {
+	return time.Parse(time.DateOnly, value)
+}
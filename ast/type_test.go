@@ -0,0 +1,63 @@
+package ast_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/matthewmueller/enroute/ast"
+	"github.com/matthewmueller/enroute/internal/parser"
+)
+
+func TestBuiltinTypes(t *testing.T) {
+	is := is.New(t)
+	intType, ok := ast.LookupType("int")
+	is.True(ok)
+	value, err := intType.Parse("42")
+	is.NoErr(err)
+	is.Equal(value, 42)
+	_, err = intType.Parse("4x")
+	is.True(err != nil)
+	uuidType, ok := ast.LookupType("uuid")
+	is.True(ok)
+	value, err = uuidType.Parse("123e4567-e89b-12d3-a456-426614174000")
+	is.NoErr(err)
+	is.Equal(value, "123e4567-e89b-12d3-a456-426614174000")
+	_, err = uuidType.Parse("123e4567-e89b-12d3-a456-42661417400")
+	is.Equal(err.Error(), `invalid uuid "123e4567-e89b-12d3-a456-42661417400"`)
+	_, err = uuidType.Parse("123e4567xe89b-12d3-a456-426614174000")
+	is.True(err != nil)
+	_, err = uuidType.Parse("123e4567-e89b-12d3-a456-42661417400g")
+	is.True(err != nil)
+	dateType, ok := ast.LookupType("date")
+	is.True(ok)
+	value, err = dateType.Parse("2024-02-29")
+	is.NoErr(err)
+	is.Equal(value, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC))
+	_, err = dateType.Parse("2023-02-29")
+	is.True(err != nil)
+	_, ok = ast.LookupType("float")
+	is.True(!ok)
+}
+
+func TestRegisterType(t *testing.T) {
+	is := is.New(t)
+	// Types are global, so the name shouldn't clash with other tests
+	ast.RegisterType("test_even", ast.TypeFunc(func(value string) (any, error) {
+		if len(value)%2 != 0 {
+			return nil, fmt.Errorf("odd length %q", value)
+		}
+		return len(value), nil
+	}))
+	even, ok := ast.LookupType("test_even")
+	is.True(ok)
+	value, err := even.Parse("ab")
+	is.NoErr(err)
+	is.Equal(value, 2)
+	_, err = even.Parse("abc")
+	is.Equal(err.Error(), `odd length "abc"`)
+	route, err := parser.Parse("/{name:test_even}")
+	is.NoErr(err)
+	is.Equal(route.String(), "/{name:test_even}")
+}
@@ -0,0 +1,12 @@
+// Command enroute lists, tests and lints route files
+package main
+
+import (
+	"os"
+
+	"github.com/matthewmueller/enroute/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.New().Run(os.Args[1:]...))
+}
@@ -0,0 +1,130 @@
+package enroute
+
+import (
+	"iter"
+	"sync"
+	"sync/atomic"
+)
+
+// NewConcurrent creates a tree that's safe to use from multiple goroutines
+func NewConcurrent[V any](options ...Option) *Concurrent[V] {
+	c := &Concurrent[V]{}
+	c.tree.Store(NewTree[V](options...))
+	return c
+}
+
+// Concurrent is a tree that's safe to use from multiple goroutines. Matches
+// read from an immutable snapshot of the tree without locking. Changes are
+// applied to a copy of the tree that's swapped in atomically once the change
+// succeeds.
+type Concurrent[V any] struct {
+	mu   sync.Mutex // Serializes changes
+	tree atomic.Pointer[Tree[V]]
+}
+
+// Snapshot returns the current version of the tree. The snapshot must not be
+// modified, use Update instead.
+func (c *Concurrent[V]) Snapshot() *Tree[V] {
+	if tree := c.tree.Load(); tree != nil {
+		return tree
+	}
+	return new(Tree[V])
+}
+
+// Update applies changes to a copy of the tree. If fn succeeds, the copy
+// replaces the current version of the tree. If fn fails, none of the changes
+// are applied. This can be used to reload many routes at once.
+func (c *Concurrent[V]) Update(fn func(tree *Tree[V]) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	next := c.Snapshot().Clone()
+	if err := fn(next); err != nil {
+		return err
+	}
+	c.tree.Store(next)
+	return nil
+}
+
+// Insert a route that maps to a value into the tree
+func (c *Concurrent[V]) Insert(route string, value V) error {
+	return c.Update(func(tree *Tree[V]) error {
+		return tree.Insert(route, value)
+	})
+}
+
+// InsertMethod inserts a route that only handles the given method
+func (c *Concurrent[V]) InsertMethod(method string, route string, value V) error {
+	return c.Update(func(tree *Tree[V]) error {
+		return tree.InsertMethod(method, route, value)
+	})
+}
+
+// InsertNamed inserts a route under a name that can be used to build URLs
+func (c *Concurrent[V]) InsertNamed(name string, route string, value V) error {
+	return c.Update(func(tree *Tree[V]) error {
+		return tree.InsertNamed(name, route, value)
+	})
+}
+
+// Annotate the route with metadata
+func (c *Concurrent[V]) Annotate(route string, meta *Meta) error {
+	return c.Update(func(tree *Tree[V]) error {
+		return tree.Annotate(route, meta)
+	})
+}
+
+// Mount the sub tree's routes under the prefix
+func (c *Concurrent[V]) Mount(prefix string, sub *Tree[V]) error {
+	return c.Update(func(tree *Tree[V]) error {
+		return tree.Mount(prefix, sub)
+	})
+}
+
+// Delete a route and all of its expansions from the tree
+func (c *Concurrent[V]) Delete(route string) error {
+	return c.Update(func(tree *Tree[V]) error {
+		return tree.Delete(route)
+	})
+}
+
+// MatchAll returns every route that matches the path in match order
+func (c *Concurrent[V]) MatchAll(input string) []*Match[V] {
+	return c.Snapshot().MatchAll(input)
+}
+
+// MatchAllMethod returns every route that matches the path and handles the
+// method in match order
+func (c *Concurrent[V]) MatchAllMethod(method string, input string) []*Match[V] {
+	return c.Snapshot().MatchAllMethod(method, input)
+}
+
+// Routes yields each route in the current version of the tree once
+func (c *Concurrent[V]) Routes() iter.Seq[*RouteInfo[V]] {
+	return c.Snapshot().Routes()
+}
+
+// SortedRoutes yields each route in the current version of the tree once,
+// ordered by match priority
+func (c *Concurrent[V]) SortedRoutes() iter.Seq[*RouteInfo[V]] {
+	return c.Snapshot().SortedRoutes()
+}
+
+// Match a input path to a route
+func (c *Concurrent[V]) Match(input string) (*Match[V], error) {
+	return c.Snapshot().Match(input)
+}
+
+// MatchMethod matches an input path to a route that handles the method
+func (c *Concurrent[V]) MatchMethod(method string, input string) (*Match[V], error) {
+	return c.Snapshot().MatchMethod(method, input)
+}
+
+// URL builds a path from the named route, filling in the route's slots
+func (c *Concurrent[V]) URL(name string, slots ...*Slot) (string, error) {
+	return c.Snapshot().URL(name, slots...)
+}
+
+// String returns the current version of the tree as a string
+func (c *Concurrent[V]) String() string {
+	return c.Snapshot().String()
+}
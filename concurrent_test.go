@@ -0,0 +1,161 @@
+package enroute_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/matthewmueller/diff"
+	"github.com/matthewmueller/enroute"
+)
+
+func TestClone(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.InsertNamed("show", "/users/{id}", "users/show"))
+	is.NoErr(tree.Insert("/users/{id}/edit", "users/edit"))
+	before := tree.String()
+	clone := tree.Clone()
+	is.NoErr(clone.Insert("/users/{id}.{format}", "users/show"))
+	is.NoErr(clone.InsertNamed("new", "/users/new", "users/new"))
+	is.NoErr(clone.Delete("/users/{id}/edit"))
+	diff.TestString(t, tree.String(), before)
+	// The original tree is unchanged, including the slot delimiters
+	match, err := tree.Match("/users/10.json")
+	is.NoErr(err)
+	is.Equal(match.String(), "/users/{id} id=10.json")
+	match, err = tree.Match("/users/10/edit")
+	is.NoErr(err)
+	is.Equal(match.Value, "users/edit")
+	_, err = tree.URL("new")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	// The clone has the changes
+	match, err = clone.Match("/users/10.json")
+	is.NoErr(err)
+	is.Equal(match.String(), "/users/{id}.{format} id=10&format=json")
+	_, err = clone.Match("/users/10/edit")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	url, err := clone.URL("show", &enroute.Slot{Key: "id", Value: "10"})
+	is.NoErr(err)
+	is.Equal(url, "/users/10")
+}
+
+func TestConcurrent(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.NewConcurrent[string]()
+	_, err := tree.Match("/")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	is.Equal(tree.String(), "")
+	is.NoErr(tree.Insert("/", "index"))
+	is.NoErr(tree.InsertNamed("users.show", "/users/{id}", "users/show"))
+	is.NoErr(tree.InsertMethod("POST", "/users", "users/create"))
+	match, err := tree.Match("/users/10")
+	is.NoErr(err)
+	is.Equal(match.Value, "users/show")
+	match, err = tree.MatchMethod("POST", "/users")
+	is.NoErr(err)
+	is.Equal(match.Value, "users/create")
+	url, err := tree.URL("users.show", &enroute.Slot{Key: "id", Value: "10"})
+	is.NoErr(err)
+	is.Equal(url, "/users/10")
+	is.True(errors.Is(tree.Insert("/users/{id}", "users/show"), enroute.ErrDuplicate))
+	is.NoErr(tree.Delete("/users/{id}"))
+	_, err = tree.Match("/users/10")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+}
+
+func TestConcurrentUpdate(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.NewConcurrent[string]()
+	is.NoErr(tree.Insert("/", "index"))
+	snapshot := tree.Snapshot()
+	// Failed updates aren't applied
+	err := tree.Update(func(tree *enroute.Tree[string]) error {
+		if err := tree.Insert("/about", "about"); err != nil {
+			return err
+		}
+		return tree.Insert("/", "index")
+	})
+	is.True(errors.Is(err, enroute.ErrDuplicate))
+	_, err = tree.Match("/about")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	// Successful updates are applied at once
+	is.NoErr(tree.Update(func(tree *enroute.Tree[string]) error {
+		if err := tree.Delete("/"); err != nil {
+			return err
+		}
+		return tree.Insert("/about", "about")
+	}))
+	match, err := tree.Match("/about")
+	is.NoErr(err)
+	is.Equal(match.Value, "about")
+	_, err = tree.Match("/")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	// Old snapshots are unchanged
+	match, err = snapshot.Match("/")
+	is.NoErr(err)
+	is.Equal(match.Value, "index")
+	_, err = snapshot.Match("/about")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+}
+
+func TestConcurrentRace(t *testing.T) {
+	tree := enroute.NewConcurrent[string]()
+	noErr(t, tree.Insert("/{name}", "name"))
+	noErr(t, tree.Insert("/users/{id}", "users"))
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := range 50 {
+				route := fmt.Sprintf("/users/{id}/%d-%d-{slug}", i, j)
+				if err := tree.Insert(route, route); err != nil {
+					t.Error(err)
+					return
+				}
+				if j%2 == 0 {
+					if err := tree.Delete(route); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 200 {
+				match, err := tree.Match("/users/10")
+				if err != nil {
+					t.Error(err)
+					return
+				} else if match.Value != "users" {
+					t.Errorf("unexpected match %s", match)
+					return
+				}
+				if _, err := tree.Match("/bob"); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for i := range 4 {
+		for j := range 50 {
+			match, err := tree.Match(fmt.Sprintf("/users/10/%d-%d-slug", i, j))
+			if j%2 == 0 {
+				if !errors.Is(err, enroute.ErrNoMatch) {
+					t.Fatalf("expected no match, got %v", err)
+				}
+				continue
+			}
+			noErr(t, err)
+			if match.Slots[1].Value != "slug" {
+				t.Fatalf("unexpected match %s", match)
+			}
+		}
+	}
+}
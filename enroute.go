@@ -2,18 +2,86 @@ package enroute
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/matthewmueller/enroute/ast"
 	"github.com/matthewmueller/enroute/internal/parser"
+	"golang.org/x/text/unicode/norm"
 )
 
 var ErrDuplicate = fmt.Errorf("route")
 var ErrNoMatch = fmt.Errorf("no match")
 
-func New() *Tree {
-	return &Tree{}
+// New string tree
+func New(options ...Option) *Tree[string] {
+	return NewTree[string](options...)
+}
+
+// NewTree creates a tree that maps routes to values of any type
+func NewTree[V any](options ...Option) *Tree[V] {
+	t := &Tree[V]{}
+	for _, option := range options {
+		option(&t.config)
+	}
+	return t
+}
+
+// Option configures the tree
+type Option func(c *config)
+
+type config struct {
+	casing       Case
+	encodedSlash EncodedSlash
+}
+
+// Case is how the tree matches the casing of paths
+type Case uint8
+
+const (
+	// CaseInsensitive matches paths regardless of their casing. Routes must be
+	// lowercase. This is the default.
+	CaseInsensitive Case = iota
+	// CaseSensitive only matches paths with the exact casing of the route.
+	// Routes may contain uppercase letters.
+	CaseSensitive
+	// CaseRedirect matches paths regardless of their casing, but sets
+	// Match.Redirect to the path in the route's casing when the casing differs.
+	// Routes may contain uppercase letters.
+	CaseRedirect
+)
+
+// WithCase sets how the tree matches the casing of paths
+func WithCase(casing Case) Option {
+	return func(c *config) {
+		c.casing = casing
+	}
+}
+
+// EncodedSlash is how the tree handles encoded slashes (%2F) in slot values.
+// Encoded slashes never separate path segments.
+type EncodedSlash uint8
+
+const (
+	// DecodeEncodedSlash allows encoded slashes in slots and decodes them to "/"
+	// in the slot's value. This is the default.
+	DecodeEncodedSlash EncodedSlash = iota
+	// KeepEncodedSlash allows encoded slashes in slots, but keeps them encoded
+	// in the slot's value, while decoding everything else.
+	KeepEncodedSlash
+	// RejectEncodedSlash doesn't match slots with encoded slashes
+	RejectEncodedSlash
+)
+
+// WithEncodedSlash sets how the tree handles encoded slashes in slot values
+func WithEncodedSlash(policy EncodedSlash) Option {
+	return func(c *config) {
+		c.encodedSlash = policy
+	}
 }
 
 // Parse a route
@@ -21,120 +89,280 @@ func Parse(route string) (*ast.Route, error) {
 	return parser.Parse(route)
 }
 
-type Tree struct {
-	root *Node
+// RegisterType registers a slot type for routes like /users/{id:int}. The
+// built-in types are int, uuid and date.
+func RegisterType(name string, fn func(value string) (any, error)) {
+	ast.RegisterType(name, ast.TypeFunc(fn))
+}
+
+// Build a path from a route by filling its slots with values
+func Build(route string, values map[string]string) (string, error) {
+	r, err := parser.Parse(trimTrailingSlash(route))
+	if err != nil {
+		return "", err
+	}
+	return r.Build(values)
+}
+
+type Tree[V any] struct {
+	root   *Node[V]
+	hosts  *Tree[string]       // Host patterns in match order
+	trees  map[string]*Tree[V] // Path trees for each host pattern
+	names  map[string]*ast.Route
+	config config
+}
+
+// parse a route with the tree's configuration
+func (t *Tree[V]) parse(route string) (*ast.Route, error) {
+	route = trimTrailingSlash(route)
+	switch t.config.casing {
+	case CaseSensitive:
+		return parser.Parse(route, parser.CaseSensitive())
+	case CaseRedirect:
+		return parser.Parse(route, parser.AllowUppercase())
+	default:
+		return parser.Parse(route)
+	}
 }
 
 // MustInsert panics if the route is invalid
-func (t *Tree) MustInsert(route string, key string) {
-	if err := t.Insert(route, key); err != nil {
+func (t *Tree[V]) MustInsert(route string, value V) {
+	if err := t.Insert(route, value); err != nil {
 		panic(err)
 	}
 }
 
-// Insert a route that maps to a key into the tree
-func (t *Tree) Insert(route string, key string) error {
-	r, err := parser.Parse(trimTrailingSlash(route))
+// Insert a route that maps to a value into the tree
+func (t *Tree[V]) Insert(route string, value V) error {
+	return t.insertRoute(route, &Node[V]{Value: value})
+}
+
+// InsertMethod inserts a route that only handles the given method (e.g. GET).
+// The same route can be inserted once for each method. Routes inserted with a
+// method are matched with MatchMethod and have an empty Value.
+func (t *Tree[V]) InsertMethod(method string, route string, value V) error {
+	if method == "" {
+		return fmt.Errorf("enroute: method can't be empty for %q", route)
+	}
+	return t.insertRoute(route, &Node[V]{
+		methods: map[string]V{strings.ToUpper(method): value},
+	})
+}
+
+// insertRoute inserts the route with the value or methods of the template
+func (t *Tree[V]) insertRoute(route string, template *Node[V]) error {
+	r, err := t.parse(route)
 	if err != nil {
 		return err
 	}
+	if len(r.Host) > 0 {
+		return t.insertHost(r, template)
+	}
+	return t.insertParsed(r, template)
+}
+
+func (t *Tree[V]) insertParsed(r *ast.Route, template *Node[V]) error {
 	initialRoute := r.String()
 	precedence := r.Precedence()
 	// Expand optional and wildcard routes
 	for _, route := range r.Expand() {
-		if err := t.insert(route, key, initialRoute, precedence); err != nil {
+		entry := template.with(route.Sections)
+		entry.Label = initialRoute
+		entry.precedence = precedence
+		entry.route = route
+		if err := t.insert(entry); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (t *Tree) insert(route *ast.Route, value string, initialRoute string, precedence int) error {
-	if t.root == nil {
-		t.root = &Node{
-			initialRoute,
-			value,
-			precedence,
-			route,
-			route.Sections,
-			nil,
+// Clone the tree. Changes to the clone don't affect the original tree.
+func (t *Tree[V]) Clone() *Tree[V] {
+	clone := &Tree[V]{
+		names:  maps.Clone(t.names),
+		config: t.config,
+	}
+	if t.root != nil {
+		clone.root = t.root.clone()
+	}
+	if t.hosts != nil {
+		clone.hosts = t.hosts.Clone()
+		clone.trees = make(map[string]*Tree[V], len(t.trees))
+		for host, tree := range t.trees {
+			clone.trees[host] = tree.Clone()
 		}
+	}
+	return clone
+}
+
+// InsertNamed inserts a route under a name that can be used to build URLs
+func (t *Tree[V]) InsertNamed(name string, route string, value V) error {
+	return t.insertNamed(name, route, &Node[V]{Value: value})
+}
+
+func (t *Tree[V]) insertNamed(name string, route string, template *Node[V]) error {
+	if _, ok := t.names[name]; ok {
+		return fmt.Errorf("%w name already exists %q", ErrDuplicate, name)
+	}
+	r, err := t.parse(route)
+	if err != nil {
+		return err
+	}
+	if err := t.insertRoute(route, template); err != nil {
+		return err
+	}
+	if t.names == nil {
+		t.names = map[string]*ast.Route{}
+	}
+	t.names[name] = r
+	return nil
+}
+
+// URL builds a path from the named route, filling in the route's slots. The
+// slot values are escaped, so the slots of a match build the path it matched.
+func (t *Tree[V]) URL(name string, slots ...*Slot) (string, error) {
+	route, ok := t.names[name]
+	if !ok {
+		return "", fmt.Errorf("%w for name %q", ErrNoMatch, name)
+	}
+	values := make(map[string]string, len(slots))
+	for _, slot := range slots {
+		values[slot.Key] = slot.Value
+	}
+	return route.Build(values)
+}
+
+func (t *Tree[V]) insert(entry *Node[V]) error {
+	if t.root == nil {
+		t.root = entry
 		return nil
 	}
-	return t.root.insert(route, value, t.root, initialRoute, precedence, route.Sections)
+	return t.root.insert(t.root, entry, entry.sections)
 }
 
-type Node struct {
+type Node[V any] struct {
 	Label      string
-	Value      string
+	Value      V
+	Meta       *Meta // Metadata about the route, nil if there isn't any
 	precedence int
 	route      *ast.Route
+	methods    map[string]V
 	sections   ast.Sections
-	children   nodes
+	children   nodes[V]
+	variants   nodes[V] // Routes with the same path, but a different query
+	shadowed   nodes[V] // Routes with the same path, but a lower precedence
+	group      *Group[V]
 }
 
-func (n *Node) priority() (priority int) {
+func (n *Node[V]) priority() (priority int) {
 	if len(n.sections) == 0 {
 		return 0
 	}
 	return n.sections[0].Priority()
 }
 
-type nodes []*Node
+// Methods returns the methods the node handles in sorted order. Nodes inserted
+// without a method return nil.
+func (n *Node[V]) Methods() []string {
+	if n.methods == nil {
+		return nil
+	}
+	methods := make([]string, 0, len(n.methods))
+	for method := range n.methods {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// clone the node and its descendants
+func (n *Node[V]) clone() *Node[V] {
+	node := n.with(n.sections.Clone())
+	node.children = make(nodes[V], len(n.children))
+	for i, child := range n.children {
+		node.children[i] = child.clone()
+	}
+	return node
+}
+
+// with copies the node, replacing its sections
+func (n *Node[V]) with(sections ast.Sections) *Node[V] {
+	node := *n
+	node.methods = maps.Clone(n.methods)
+	node.sections = sections
+	if n.variants != nil {
+		node.variants = make(nodes[V], len(n.variants))
+		for i, variant := range n.variants {
+			node.variants[i] = variant.with(sections)
+		}
+	}
+	node.shadowed = slices.Clone(n.shadowed)
+	return &node
+}
+
+// set the node's route from the entry
+func (n *Node[V]) set(entry *Node[V]) {
+	n.Label = entry.Label
+	n.Value = entry.Value
+	n.Meta = entry.Meta
+	n.precedence = entry.precedence
+	n.route = entry.route
+	n.methods = maps.Clone(entry.methods)
+	n.variants = entry.variants
+	n.shadowed = entry.shadowed
+	n.group = entry.group
+}
+
+// unset the node's route, turning it into a split in the tree
+func (n *Node[V]) unset() {
+	var zero V
+	n.Label = ""
+	n.Value = zero
+	n.Meta = nil
+	n.precedence = 0
+	n.route = nil
+	n.methods = nil
+	n.variants = nil
+	n.shadowed = nil
+	n.group = nil
+}
+
+type nodes[V any] []*Node[V]
 
-var _ sort.Interface = (*nodes)(nil)
+var _ sort.Interface = (*nodes[string])(nil)
 
-func (n nodes) Len() int {
+func (n nodes[V]) Len() int {
 	return len(n)
 }
 
-func (n nodes) Less(i, j int) bool {
+func (n nodes[V]) Less(i, j int) bool {
 	return n[i].priority() > n[j].priority()
 }
 
-func (n nodes) Swap(i, j int) {
+func (n nodes[V]) Swap(i, j int) {
 	n[i], n[j] = n[j], n[i]
 }
 
-func (n *Node) insert(route *ast.Route, value string, parent *Node, initialRoute string, precedence int, sections ast.Sections) error {
+func (n *Node[V]) insert(parent *Node[V], entry *Node[V], sections ast.Sections) error {
 	lcp := n.sections.LongestCommonPrefix(sections)
 	if lcp < n.sections.Len() {
 		// Split the node's sections
 		parts := n.sections.Split(lcp)
 		// Create a new node with the parent's sections after the lcp.
-		splitChild := &Node{
-			n.Label,
-			n.Value,
-			n.precedence,
-			n.route,
-			parts[1],
-			n.children,
-		}
+		splitChild := n.with(parts[1])
 		n.sections = parts[0]
-		n.children = nodes{splitChild}
+		n.children = nodes[V]{splitChild}
 		// Add a new child if we have more sections left.
 		if lcp < sections.Len() {
-			newChild := &Node{
-				initialRoute,
-				value,
-				precedence,
-				route,
-				sections.Split(lcp)[1],
-				nil,
-			}
+			newChild := entry.with(sections.Split(lcp)[1])
 			// Replace the parent's sections with the lcp.
 			n.children = append(n.children, newChild)
-			n.Label = ""
-			n.route = nil
-			n.precedence = 0
-			n.Value = ""
+			n.unset()
 		} else {
 			// Otherwise this route matches the parent. Update the parent's route and
 			// path.
-			n.Label = initialRoute
-			n.route = route
-			n.Value = value
-			n.precedence = precedence
+			n.set(entry)
 		}
 		sort.Sort(n.children)
 		return nil
@@ -143,87 +371,281 @@ func (n *Node) insert(route *ast.Route, value string, parent *Node, initialRoute
 	if lcp == sections.Len() {
 		// This node was for a split in the tree, but doesn't have a route yet
 		if n.route == nil {
-			n.Label = initialRoute
-			n.route = route
-			n.Value = value
+			n.set(entry)
 			return nil
 		}
-		oldRoute := n.route.String()
-		newRoute := route.String()
-
-		// If the route is the same, update the path and precedence
-		if n.precedence < precedence {
-			if oldRoute == newRoute {
-				n.Label = initialRoute
-				n.route = route
-				n.Value = value
-				n.precedence = precedence
-			} else {
-				parent.children = append(parent.children, &Node{
-					initialRoute,
-					value,
-					precedence,
-					route,
-					sections,
-					nil,
-				})
-				sort.Sort(parent.children)
+		// The same path with different required query parameters
+		if querySignature(n.route) != querySignature(entry.route) {
+			return n.insertVariant(entry)
+		}
+		// The same route is being inserted for another method
+		if n.Label == entry.Label && n.methods != nil && entry.methods != nil {
+			if method, owner, ok := n.conflict(entry); ok && owner == n.Label {
+				return fmt.Errorf("%w already exists %s %q", ErrDuplicate, method, n.Label)
+			} else if ok {
+				return fmt.Errorf("%w %s %q is ambiguous with %q", ErrDuplicate, method, entry.Label, owner)
 			}
+			maps.Copy(n.methods, entry.methods)
 			return nil
 		}
-		if n.precedence > precedence && parent != nil {
-			if oldRoute == newRoute {
-				return nil
-			}
-			parent.children = append(parent.children, &Node{
-				initialRoute,
-				value,
-				precedence,
-				route,
-				sections,
-				nil,
-			})
+		oldRoute := n.route.String()
+		newRoute := entry.route.String()
+
+		// If the route is the same, the route with the higher precedence wins
+		if oldRoute == newRoute && n.precedence != entry.precedence {
+			return n.shadow(entry)
+		}
+		if n.precedence < entry.precedence {
+			parent.children = append(parent.children, entry.with(sections))
+			sort.Sort(parent.children)
+			return nil
+		}
+		if n.precedence > entry.precedence && parent != nil {
+			parent.children = append(parent.children, entry.with(sections))
 			sort.Sort(parent.children)
 			return nil
 		}
 		if newRoute == oldRoute {
 			return fmt.Errorf("%w already exists %q", ErrDuplicate, oldRoute)
 		} else {
-			return fmt.Errorf("%w %q is ambiguous with %q", ErrDuplicate, initialRoute, n.Label)
+			return fmt.Errorf("%w %q is ambiguous with %q", ErrDuplicate, entry.Label, n.Label)
 		}
 	}
 	// Check children for a match
 	remainingSections := sections.Split(lcp)[1]
 	for _, child := range n.children {
-		if child.sections.At(0) == remainingSections.At(0) {
-			return child.insert(route, value, n, initialRoute, precedence, remainingSections)
+		if sameStart(child.sections, remainingSections) {
+			return child.insert(n, entry, remainingSections)
 		}
 	}
-	n.children = append(n.children, &Node{
-		initialRoute,
-		value,
-		precedence,
-		route,
-		remainingSections,
-		nil,
-	})
+	n.children = append(n.children, entry.with(remainingSections))
 	sort.Sort(n.children)
 	return nil
 }
 
+// shadow keeps the route with the lower precedence on the node, so it can be
+// restored when the route with the higher precedence is deleted. Routes with
+// methods share the node when their methods don't collide.
+func (n *Node[V]) shadow(entry *Node[V]) error {
+	if method, owner, ok := n.conflict(entry); ok {
+		return fmt.Errorf("%w %s %q is ambiguous with %q", ErrDuplicate, method, entry.Label, owner)
+	}
+	if n.precedence > entry.precedence {
+		n.shadowed = append(n.shadowed, entry)
+	} else {
+		// Keep the node's route without its variants and shadowed routes
+		old := new(Node[V])
+		old.set(n)
+		old.methods = n.ownMethods()
+		old.variants, old.shadowed = nil, nil
+		variants, shadowed := n.variants, n.shadowed
+		n.set(entry)
+		n.variants, n.shadowed = variants, append(shadowed, old)
+	}
+	sort.SliceStable(n.shadowed, func(i, j int) bool {
+		return n.shadowed[i].precedence > n.shadowed[j].precedence
+	})
+	n.mergeMethods()
+	return nil
+}
+
+// conflict returns a method of the entry that's already handled by one of the
+// node's routes, along with that route
+func (n *Node[V]) conflict(entry *Node[V]) (method, owner string, ok bool) {
+	for _, method := range entry.Methods() {
+		for _, shadowed := range n.shadowed {
+			if _, ok := shadowed.methods[method]; ok {
+				return method, shadowed.Label, true
+			}
+		}
+		if _, ok := n.methods[method]; ok {
+			return method, n.Label, true
+		}
+	}
+	return "", "", false
+}
+
+// mergeMethods merges the methods of the shadowed routes into the node's
+// methods. Nodes without methods already handle every method.
+func (n *Node[V]) mergeMethods() {
+	if n.methods == nil {
+		return
+	}
+	for _, shadowed := range n.shadowed {
+		maps.Copy(n.methods, shadowed.methods)
+	}
+}
+
+// ownMethods returns the methods of the node's route without the methods
+// merged from shadowed routes
+func (n *Node[V]) ownMethods() map[string]V {
+	if n.methods == nil {
+		return nil
+	}
+	methods := maps.Clone(n.methods)
+	for _, shadowed := range n.shadowed {
+		for method := range shadowed.methods {
+			delete(methods, method)
+		}
+	}
+	return methods
+}
+
+// restore the shadowed route with the highest precedence or promote the next
+// variant after the node's route was deleted
+func (n *Node[V]) restore() {
+	variants, shadowed := n.variants, n.shadowed
+	n.unset()
+	switch {
+	case len(shadowed) > 0:
+		n.set(shadowed[0])
+		n.variants, n.shadowed = variants, shadowed[1:]
+		n.mergeMethods()
+	case len(variants) > 0:
+		n.set(variants[0])
+		n.variants = variants[1:]
+	}
+}
+
+// Delete a route and all of its expansions from the tree
+func (t *Tree[V]) Delete(route string) error {
+	r, err := t.parse(route)
+	if err != nil {
+		return err
+	}
+	label := r.String()
+	if len(r.Host) > 0 {
+		if !t.deleteHost(r.Host, label) {
+			return fmt.Errorf("%w for %s", ErrNoMatch, route)
+		}
+	} else if !t.delete(label) {
+		return fmt.Errorf("%w for %s", ErrNoMatch, route)
+	}
+	// Remove any names pointing to the route
+	for name, r := range t.names {
+		if r.String() == label {
+			delete(t.names, name)
+		}
+	}
+	return nil
+}
+
+// delete the route with the label from the tree
+func (t *Tree[V]) delete(label string) bool {
+	if t.root == nil || !t.root.delete(label) {
+		return false
+	}
+	// Compact the root if it's no longer routable
+	if t.root.Label == "" {
+		switch len(t.root.children) {
+		case 0:
+			t.root = nil
+		case 1:
+			t.root.merge()
+		}
+	}
+	return true
+}
+
+// delete the route with the given label from the node and its descendants,
+// compacting the children that are no longer routable.
+func (n *Node[V]) delete(label string) (deleted bool) {
+	for i, shadowed := range n.shadowed {
+		if shadowed.Label == label {
+			// Stop handling the methods that were merged from the route
+			for method := range shadowed.methods {
+				delete(n.methods, method)
+			}
+			n.shadowed = append(n.shadowed[:i:i], n.shadowed[i+1:]...)
+			deleted = true
+			break
+		}
+	}
+	if n.Label == label {
+		n.restore()
+		deleted = true
+	}
+	for i, variant := range n.variants {
+		if variant.Label == label {
+			n.variants = append(n.variants[:i:i], n.variants[i+1:]...)
+			deleted = true
+			break
+		}
+	}
+	children := make(nodes[V], 0, len(n.children))
+	for _, child := range n.children {
+		if child.delete(label) {
+			deleted = true
+		}
+		if child.Label == "" {
+			switch len(child.children) {
+			case 0:
+				// Remove leaves that are no longer routable
+				continue
+			case 1:
+				// Merge split nodes that are left with a single child
+				child.merge()
+			}
+		}
+		children = append(children, child)
+	}
+	n.children = children
+	return deleted
+}
+
+// merge the node with its only child
+func (n *Node[V]) merge() {
+	child := n.children[0]
+	n.set(child)
+	n.sections = n.sections.Join(child.sections)
+	n.children = child.children
+}
+
 type Slot struct {
-	Key   string
+	Key string
+	// Value of the slot with percent-encoded characters decoded. Building a
+	// URL from the value encodes it again.
 	Value string
+	// Raw value of the slot in the path as it was given, with its encoded
+	// characters still encoded
+	Raw string
+	// Parsed value for typed slots like {id:int}
+	Parsed any
 }
 
-func createSlots(r *ast.Route, slotValues []string) (slots []*Slot) {
+// slotValue is a slot's value that was cut from the path while matching
+type slotValue struct {
+	normalized string // Value in the normalized path, still encoded
+	raw        string // Value in the original path
+	parsed     any    // Parsed value for typed slots
+}
+
+// matchSection matches the section at the start of the path, returning the
+// slot it cut, if any. Typed slots return the value they parsed while
+// matching, so it doesn't need to be parsed again.
+func matchSection(section ast.Section, path string) (index int, slot slotValue, ok bool) {
+	if typed, ok := section.(*ast.TypedSlot); ok {
+		index, slot.normalized, slot.parsed = typed.MatchParsed(path)
+		return index, slot, index > 0
+	}
+	index, slots := section.Match(path)
+	if len(slots) == 0 {
+		return index, slot, false
+	}
+	slot.normalized = slots[0]
+	return index, slot, true
+}
+
+func createSlots(r *ast.Route, slotValues []slotValue, policy EncodedSlash) (slots []*Slot) {
 	index := 0
 	for _, section := range r.Sections {
-		switch s := section.(type) {
-		case ast.Slot:
+		if s, ok := section.(ast.Slot); ok {
+			value := slotValues[index]
 			slots = append(slots, &Slot{
-				Key:   s.Slot(),
-				Value: slotValues[index],
+				Key:    s.Slot(),
+				Value:  decodeSlot(value.normalized, policy),
+				Raw:    value.raw,
+				Parsed: value.parsed,
 			})
 			index++
 		}
@@ -231,15 +653,96 @@ func createSlots(r *ast.Route, slotValues []string) (slots []*Slot) {
 	return slots
 }
 
+// decodeSlot decodes the slot's raw value. The raw value has already been
+// checked while matching.
+func decodeSlot(raw string, policy EncodedSlash) string {
+	if policy != KeepEncodedSlash {
+		value, _ := ast.Unescape(raw)
+		return value
+	}
+	parts := splitEncodedSlash(raw)
+	for i, part := range parts {
+		parts[i], _ = ast.Unescape(part)
+	}
+	return strings.Join(parts, "%2F")
+}
+
+// splitEncodedSlash splits the raw value around its encoded slashes
+func splitEncodedSlash(raw string) (parts []string) {
+	for {
+		i := indexEncodedSlash(raw)
+		if i < 0 {
+			return append(parts, raw)
+		}
+		parts = append(parts, raw[:i])
+		raw = raw[i+3:]
+	}
+}
+
+// indexEncodedSlash returns the index of the first encoded slash or -1
+func indexEncodedSlash(raw string) int {
+	for i := 0; i+2 < len(raw); i++ {
+		if raw[i] == '%' && raw[i+1] == '2' && (raw[i+2] == 'F' || raw[i+2] == 'f') {
+			return i
+		}
+	}
+	return -1
+}
+
 // Match represents a route that matches a path
-type Match struct {
+type Match[V any] struct {
 	Route string
 	Path  string
 	Slots []*Slot
-	Value string
+	Value V
+	Meta  *Meta // Metadata about the route, nil if there isn't any
+
+	// Redirect is the path in the route's casing when the tree uses
+	// CaseRedirect and the path's casing differs. Empty otherwise. The
+	// redirect never includes the host.
+	Redirect string
+
+	next *Match[V]
+}
+
+// Get the value of a slot by its key
+func (m *Match[V]) Get(key string) (string, bool) {
+	for _, slot := range m.Slots {
+		if slot.Key == key {
+			return slot.Value, true
+		}
+	}
+	return "", false
 }
 
-func (m *Match) String() string {
+// Map of slot keys to values
+func (m *Match[V]) Map() map[string]string {
+	values := make(map[string]string, len(m.Slots))
+	for _, slot := range m.Slots {
+		values[slot.Key] = slot.Value
+	}
+	return values
+}
+
+// Int returns the value of a slot as an integer
+func (m *Match[V]) Int(key string) (int, error) {
+	for _, slot := range m.Slots {
+		if slot.Key != key {
+			continue
+		}
+		if n, ok := slot.Parsed.(int); ok {
+			return n, nil
+		}
+		n, err := strconv.Atoi(slot.Value)
+		if err != nil {
+			return 0, &ast.ErrInvalidSlot{Slot: key, Value: slot.Value}
+		}
+		return n, nil
+	}
+	return 0, &ast.ErrMissingSlot{Slot: key}
+}
+
+func (m *Match[V]) String() string {
 	s := new(strings.Builder)
 	s.WriteString(m.Route)
 	if len(m.Slots) > 0 {
@@ -257,13 +760,43 @@ func (m *Match) String() string {
 }
 
 // Match a input path to a route
-func (t *Tree) Match(input string) (*Match, error) {
-	input = trimTrailingSlash(input)
-	// A tree without any routes shouldn't panic
-	if t.root == nil || len(input) == 0 || input[0] != '/' {
-		return nil, fmt.Errorf("%w for %q", ErrNoMatch, input)
+func (t *Tree[V]) Match(input string) (*Match[V], error) {
+	return t.match(new(matcher), input)
+}
+
+// MatchMethod matches an input path to a route that handles the method. Routes
+// inserted without a method handle every method. When routes match the path,
+// but none of them handle the method, an *ErrMethodNotAllowed error is returned
+// with the methods that are allowed.
+func (t *Tree[V]) MatchMethod(method string, input string) (*Match[V], error) {
+	m := &matcher{method: strings.ToUpper(method)}
+	match, err := t.match(m, input)
+	if err != nil {
+		if len(m.allowed) == 0 {
+			return nil, err
+		}
+		allowed := make([]string, 0, len(m.allowed))
+		for method := range m.allowed {
+			allowed = append(allowed, method)
+		}
+		sort.Strings(allowed)
+		path, _ := cutQuery(input)
+		return nil, &ErrMethodNotAllowed{m.method, trimTrailingSlash(path), allowed}
 	}
-	match, ok := t.root.match(input, []string{})
+	return match, nil
+}
+
+func (t *Tree[V]) match(m *matcher, input string) (*Match[V], error) {
+	input, host, path := t.prepare(m, input)
+	// Try the routes of each matching host before routes without a host
+	for _, h := range t.matchHost(host) {
+		if match, ok := t.trees[h.node.Label].matchPath(m, path); ok {
+			match.Path = input
+			match.Slots = append(createSlots(h.node.route, h.slotValues, DecodeEncodedSlash), match.Slots...)
+			return match, nil
+		}
+	}
+	match, ok := t.matchPath(m, path)
 	if !ok {
 		return nil, fmt.Errorf("%w for %q", ErrNoMatch, input)
 	}
@@ -271,50 +804,248 @@ func (t *Tree) Match(input string) (*Match, error) {
 	return match, nil
 }
 
-func (n *Node) match(path string, slotValues []string) (*Match, bool) {
+// prepare the matcher for the input, splitting the input into its host and
+// path
+func (t *Tree[V]) prepare(m *matcher, input string) (normalized, host, path string) {
+	input, query := cutQuery(input)
+	original := trimTrailingSlash(input)
+	input, m.offsets = normalizeOffsets(original)
+	host, path = splitHost(input)
+	m.original, m.length = original, len(input)
+	m.encodedSlash = t.config.encodedSlash
+	m.query = parseQuery(query)
+	return input, host, path
+}
+
+func (t *Tree[V]) matchPath(m *matcher, path string) (*Match[V], bool) {
+	// A tree without any routes shouldn't panic
+	if t.root == nil || len(path) == 0 || path[0] != '/' {
+		return nil, false
+	}
+	node, slotValues, ok := t.root.match(m, path, []slotValue{})
+	if !ok {
+		return nil, false
+	}
+	return t.newMatch(m, node, path, slotValues), true
+}
+
+// newMatch creates a match for the node
+func (t *Tree[V]) newMatch(m *matcher, node *Node[V], path string, slotValues []slotValue) *Match[V] {
+	value := node.Value
+	if node.methods != nil && m.method != "" {
+		value = node.methods[m.method]
+	}
+	match := &Match[V]{
+		Route: node.Label,
+		Path:  path,
+		Value: value,
+		Meta:  node.Meta,
+		Slots: createSlots(node.route, slotValues, t.config.encodedSlash),
+	}
+	match.Slots = append(match.Slots, querySlots(node.route, m.query)...)
+	if t.config.casing == CaseRedirect {
+		// Only redirect when the casing differs. Slots are filled with their
+		// normalized values to keep their encoding.
+		values := make(map[string]string, len(slotValues))
+		for i, slot := range match.Slots[:len(slotValues)] {
+			values[slot.Key] = slotValues[i].normalized
+		}
+		route := &ast.Route{Sections: node.route.Sections}
+		canonical, err := route.BuildEscaped(values)
+		if err == nil && canonical != path && strings.EqualFold(canonical, path) {
+			match.Redirect = canonical
+		}
+	}
+	return match
+}
+
+// sameStart is true when the sections start with the same character. Paths
+// that aren't case sensitive compare regardless of their casing.
+func sameStart(a, b ast.Sections) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return a.At(0) == b.At(0)
+	}
+	p1, ok1 := a[0].(*ast.Path)
+	p2, ok2 := b[0].(*ast.Path)
+	if ok1 && ok2 && (!p1.CaseSensitive || !p2.CaseSensitive) {
+		return strings.EqualFold(a.At(0), b.At(0))
+	}
+	return a.At(0) == b.At(0)
+}
+
+// matcher holds the state of a single match through the tree
+type matcher struct {
+	method       string                // Method to match or empty to match every method
+	allowed      map[string]bool       // Methods of routes that matched the path
+	encodedSlash EncodedSlash          // How to handle encoded slashes in slots
+	query        map[string]queryValue // Parameters in the input's query
+	original     string                // Path before it was normalized
+	offsets      []int                 // Offsets of the normalized path in the original path
+	length       int                   // Length of the normalized path
+}
+
+// raw returns the n bytes at the start of the rest of the normalized path as
+// they were in the original path
+func (m *matcher) raw(rest string, n int) string {
+	if m.offsets == nil {
+		return rest[:n]
+	}
+	start := m.length - len(rest)
+	return m.original[m.offsets[start]:m.offsets[start+n]]
+}
+
+// accept is true if the slot's raw value is properly encoded and allowed by
+// the encoded slash policy
+func (m *matcher) accept(raw string) bool {
+	if strings.IndexByte(raw, '%') < 0 {
+		return true
+	}
+	if m.encodedSlash == RejectEncodedSlash && indexEncodedSlash(raw) >= 0 {
+		return false
+	}
+	_, err := ast.Unescape(raw)
+	return err == nil
+}
+
+func (n *Node[V]) match(m *matcher, path string, slotValues []slotValue) (*Node[V], []slotValue, bool) {
+	for _, section := range n.sections {
+		if len(path) == 0 {
+			return nil, nil, false
+		}
+		index, slot, ok := matchSection(section, path)
+		if index <= 0 || ok && !m.accept(slot.normalized) {
+			return nil, nil, false
+		}
+		if ok {
+			slot.raw = m.raw(path, index)
+			slotValues = append(slotValues, slot)
+		}
+		path = path[index:]
+	}
+	if len(path) == 0 {
+		// We've reached a non-routable node
+		if n.Label == "" {
+			return nil, nil, false
+		}
+		for _, candidate := range n.candidates() {
+			if candidate.handles(m) {
+				return candidate, slotValues, true
+			}
+		}
+		return nil, nil, false
+	}
+	for _, child := range n.children {
+		if node, slotValues, ok := child.match(m, path, slotValues); ok {
+			return node, slotValues, true
+		}
+	}
+	return nil, nil, false
+}
+
+// matches calls yield with each node that matches the path in match order,
+// until yield returns false. Returns false if yield stopped the matching.
+func (n *Node[V]) matches(m *matcher, path string, slotValues []slotValue, yield func(n *Node[V], slotValues []slotValue) bool) bool {
 	for _, section := range n.sections {
 		if len(path) == 0 {
-			return nil, false
+			return true
+		}
+		index, slot, ok := matchSection(section, path)
+		if index <= 0 || ok && !m.accept(slot.normalized) {
+			return true
 		}
-		index, slots := section.Match(path)
-		if index <= 0 {
-			return nil, false
+		if ok {
+			slot.raw = m.raw(path, index)
+			slotValues = append(slotValues, slot)
 		}
 		path = path[index:]
-		slotValues = append(slotValues, slots...)
 	}
 	if len(path) == 0 {
 		// We've reached a non-routable node
 		if n.Label == "" {
-			return nil, false
+			return true
+		}
+		for _, candidate := range n.candidates() {
+			if !candidate.handles(m) {
+				continue
+			}
+			if !yield(candidate, slices.Clone(slotValues)) {
+				return false
+			}
 		}
-		return &Match{
-			Route: n.Label,
-			Value: n.Value,
-			Slots: createSlots(n.route, slotValues),
-		}, true
+		return true
 	}
 	for _, child := range n.children {
-		if match, ok := child.match(path, slotValues); ok {
-			return match, true
+		if !child.matches(m, path, slotValues, yield) {
+			return false
 		}
 	}
-	return nil, false
+	return true
+}
+
+// handles is true if the node's route accepts the query and handles the
+// method. When the route matches, but doesn't handle the method, the methods
+// it does handle are kept track of.
+func (n *Node[V]) handles(m *matcher) bool {
+	if !m.acceptQuery(n.route) {
+		return false
+	}
+	if m.method == "" || n.methods == nil {
+		return true
+	}
+	if _, ok := n.methods[m.method]; ok {
+		return true
+	}
+	if m.allowed == nil {
+		m.allowed = map[string]bool{}
+	}
+	for method := range n.methods {
+		m.allowed[method] = true
+	}
+	return false
+}
+
+// ErrMethodNotAllowed is returned when routes match the path, but none of them
+// handle the method
+type ErrMethodNotAllowed struct {
+	Method  string
+	Path    string
+	Allowed []string
+}
+
+func (e *ErrMethodNotAllowed) Error() string {
+	return fmt.Sprintf("method %s not allowed for %q, allowed %s", e.Method, e.Path, strings.Join(e.Allowed, ", "))
 }
 
 // Find by a route
-func (t *Tree) Find(route string) (*Node, error) {
-	r, err := parser.Parse(trimTrailingSlash(route))
+func (t *Tree[V]) Find(route string) (*Node[V], error) {
+	r, err := t.parse(route)
 	if err != nil {
 		return nil, err
-	} else if t.root == nil {
+	}
+	tree := t
+	if len(r.Host) > 0 {
+		if tree = t.findHost(r.Host); tree == nil {
+			return nil, fmt.Errorf("%w for %s", ErrNoMatch, route)
+		}
+	}
+	if tree.root == nil {
 		return nil, fmt.Errorf("%w for %s", ErrNoMatch, route)
 	}
-	return t.root.find(route, r.Sections)
+	node, err := tree.root.find(route, r.Sections)
+	if err != nil {
+		return nil, err
+	}
+	// Pick the route with the same query
+	if variant, ok := node.variant(r.String()); ok {
+		return variant, nil
+	} else if len(r.Query) > 0 {
+		return nil, fmt.Errorf("%w for %s", ErrNoMatch, route)
+	}
+	return node, nil
 }
 
 // Find by a route
-func (n *Node) find(route string, sections ast.Sections) (*Node, error) {
+func (n *Node[V]) find(route string, sections ast.Sections) (*Node[V], error) {
 	lcp := n.sections.LongestCommonPrefix(sections)
 	if lcp < n.sections.Len() {
 		return nil, fmt.Errorf("%w for %s", ErrNoMatch, route)
@@ -327,7 +1058,7 @@ func (n *Node) find(route string, sections ast.Sections) (*Node, error) {
 	}
 	remainingSections := sections.Split(lcp)[1]
 	for _, child := range n.children {
-		if child.sections.At(0) == remainingSections.At(0) {
+		if sameStart(child.sections, remainingSections) {
 			return child.find(route, remainingSections)
 		}
 	}
@@ -335,17 +1066,24 @@ func (n *Node) find(route string, sections ast.Sections) (*Node, error) {
 }
 
 // FindByPrefix finds a node by a prefix
-func (t *Tree) FindByPrefix(prefix string) (*Node, error) {
-	route, err := parser.Parse(trimTrailingSlash(prefix))
+func (t *Tree[V]) FindByPrefix(prefix string) (*Node[V], error) {
+	route, err := t.parse(prefix)
 	if err != nil {
 		return nil, err
-	} else if t.root == nil {
+	}
+	tree := t
+	if len(route.Host) > 0 {
+		if tree = t.findHost(route.Host); tree == nil {
+			return nil, fmt.Errorf("%w for %s", ErrNoMatch, route)
+		}
+	}
+	if tree.root == nil {
 		return nil, fmt.Errorf("%w for %s", ErrNoMatch, route)
 	}
-	return t.root.findByPrefix(prefix, route.Sections)
+	return tree.root.findByPrefix(prefix, route.Sections)
 }
 
-func (n *Node) findByPrefix(prefix string, sections ast.Sections) (*Node, error) {
+func (n *Node[V]) findByPrefix(prefix string, sections ast.Sections) (*Node[V], error) {
 	if n.sections.Len() > sections.Len() {
 		return nil, fmt.Errorf("%w for %s", ErrNoMatch, prefix)
 	}
@@ -361,7 +1099,7 @@ func (n *Node) findByPrefix(prefix string, sections ast.Sections) (*Node, error)
 	}
 	remainingSections := sections.Split(lcp)[1]
 	for _, child := range n.children {
-		if child.sections.At(0) == remainingSections.At(0) && child.sections.Len() <= remainingSections.Len() {
+		if sameStart(child.sections, remainingSections) && child.sections.Len() <= remainingSections.Len() {
 			return child.findByPrefix(prefix, remainingSections)
 		}
 	}
@@ -371,55 +1109,159 @@ func (n *Node) findByPrefix(prefix string, sections ast.Sections) (*Node, error)
 	return n, nil
 }
 
-func (t *Tree) String() string {
-	if t.root == nil {
-		return ""
+func (t *Tree[V]) String() string {
+	out := ""
+	if t.root != nil {
+		out = t.string(t.root, "")
 	}
-	return t.string(t.root, "")
+	// Routes with a host are listed under their host
+	t.eachHost(func(host string, tree *Tree[V]) {
+		out += host + "\n"
+		out += tree.string(tree.root, strings.Repeat("•", utf8.RuneCountInString(host)))
+	})
+	return out
 }
 
-func (t *Tree) string(n *Node, indent string) string {
+func (t *Tree[V]) string(n *Node[V], indent string) string {
 	route := n.sections.String()
+	out := indent + route + n.mods() + "\n"
+	// Variants share the node's path
+	for _, variant := range n.variants {
+		out += indent + route + variant.mods() + "\n"
+	}
+	indent += strings.Repeat("•", utf8.RuneCountInString(route))
+	for _, child := range n.children {
+		out += t.string(child, indent)
+	}
+	return out
+}
+
+// mods describes the node's route and methods
+func (n *Node[V]) mods() string {
 	var mods []string
 	if n.Label != "" {
 		mods = append(mods, "from="+n.Label)
 	}
-	mod := ""
-	if len(mods) > 0 {
-		mod = " [" + strings.Join(mods, ", ") + "]"
+	if n.methods != nil {
+		mods = append(mods, "methods="+strings.Join(n.Methods(), "|"))
 	}
-	out := fmt.Sprintf("%s%s%s\n", indent, route, mod)
-	for i := 0; i < len(route); i++ {
-		indent += "•"
-	}
-	for _, child := range n.children {
-		out += t.string(child, indent)
+	mods = append(mods, n.Meta.mods()...)
+	if len(mods) == 0 {
+		return ""
 	}
-	return out
+	return " [" + strings.Join(mods, ", ") + "]"
 }
 
-// Traverse the tree in depth-first order
-func (t *Tree) Each(fn func(n *Node) (next bool)) {
-	if t.root == nil {
-		return
+// Traverse the tree in depth-first order, including the nodes that split the
+// tree without a route. Use Routes to list the routes instead.
+func (t *Tree[V]) Each(fn func(n *Node[V]) (next bool)) {
+	if t.root != nil {
+		t.each(t.root, fn)
 	}
-	t.each(t.root, fn)
+	// Then traverse the routes with a host
+	t.eachHost(func(host string, tree *Tree[V]) {
+		tree.each(tree.root, fn)
+	})
 }
 
-func (t *Tree) each(n *Node, fn func(n *Node) (next bool)) {
+func (t *Tree[V]) each(n *Node[V], fn func(n *Node[V]) (next bool)) {
 	if !fn(n) {
 		return
 	}
+	for _, variant := range n.variants {
+		if !fn(variant) {
+			return
+		}
+	}
 	for _, child := range n.children {
 		t.each(child, fn)
 	}
 }
 
-// trimTrailingSlash strips any trailing slash (e.g. /users/ => /users)
+// normalize the path to NFC, so it matches routes written in any unicode form.
+// Percent-encoded unicode characters are decoded to be normalized too, while
+// encoded ASCII characters like %2F stay encoded.
+func normalize(path string) string {
+	normalized, _ := normalizeOffsets(path)
+	return normalized
+}
+
+// normalizeOffsets normalizes the path like normalize. It also returns the
+// offset in the path of each byte in the normalized path, followed by the
+// length of the path, so parts of the normalized path can be mapped back to
+// the path. The offsets are nil if the path didn't change.
+func normalizeOffsets(path string) (string, []int) {
+	decoded := path
+	var offsets []int
+	if strings.IndexByte(path, '%') >= 0 {
+		b := make([]byte, 0, len(path))
+		offsets = make([]int, 0, len(path)+1)
+		for i := 0; i < len(path); i++ {
+			offsets = append(offsets, i)
+			if path[i] == '%' && i+2 < len(path) {
+				if c, err := strconv.ParseUint(path[i+1:i+3], 16, 8); err == nil && c >= utf8.RuneSelf {
+					b = append(b, byte(c))
+					i += 2
+					continue
+				}
+			}
+			b = append(b, path[i])
+		}
+		if len(b) == len(path) {
+			// Nothing was decoded
+			offsets = nil
+		} else {
+			decoded = string(b)
+		}
+	}
+	if isASCII(decoded) || norm.NFC.IsNormalString(decoded) {
+		if offsets == nil {
+			return decoded, nil
+		}
+		return decoded, append(offsets, len(path))
+	}
+	if offsets == nil {
+		offsets = make([]int, len(decoded))
+		for i := range offsets {
+			offsets[i] = i
+		}
+	}
+	// Normalize segment by segment to keep track of where each segment started
+	var iter norm.Iter
+	iter.InitString(norm.NFC, decoded)
+	b := make([]byte, 0, len(decoded))
+	normalized := make([]int, 0, len(decoded)+1)
+	for !iter.Done() {
+		start := iter.Pos()
+		segment := iter.Next()
+		for range segment {
+			normalized = append(normalized, offsets[start])
+		}
+		b = append(b, segment...)
+	}
+	return string(b), append(normalized, len(path))
+}
+
+// isASCII is true if the path is ASCII, which is always normalized
+func isASCII(path string) bool {
+	for i := 0; i < len(path); i++ {
+		if path[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// trimTrailingSlash strips any trailing slash (e.g. /users/ => /users),
+// keeping the query if there is one
 func trimTrailingSlash(input string) string {
-	input = strings.TrimRight(input, "/")
-	if len(input) == 0 {
-		return "/"
+	path, query := cutQuery(input)
+	path = strings.TrimRight(path, "/")
+	if len(path) == 0 {
+		path = "/"
+	}
+	if len(query) > 0 {
+		return path + "?" + query
 	}
-	return input
+	return path
 }
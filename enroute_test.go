@@ -5,15 +5,17 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"slices"
 
 	"github.com/matryer/is"
 	"github.com/matthewmueller/diff"
 	"github.com/matthewmueller/enroute"
+	"github.com/matthewmueller/enroute/ast"
 )
 
-func insertEqual(t *testing.T, tree *enroute.Tree, route string, expected string) {
+func insertEqual(t *testing.T, tree *enroute.Tree[string], route string, expected string) {
 	t.Helper()
 	t.Run(route, func(t *testing.T) {
 		t.Helper()
@@ -362,6 +364,172 @@ func TestInvalidSlot(t *testing.T) {
 	insertEqual(t, tree, "/{a}{b}", `slot "a" can't have another slot after`)
 }
 
+func deleteEqual(t *testing.T, tree *enroute.Tree[string], route string, expected string) {
+	t.Helper()
+	t.Run(route, func(t *testing.T) {
+		t.Helper()
+		if err := tree.Delete(route); err != nil {
+			if err.Error() == expected {
+				return
+			}
+			t.Fatal(err)
+		}
+		actual := strings.TrimSpace(tree.String())
+		expected = strings.ReplaceAll(strings.TrimSpace(expected), "\t", "")
+		diff.TestString(t, actual, expected)
+	})
+}
+
+func TestDelete(t *testing.T) {
+	tree := enroute.New()
+	insertEqual(t, tree, "/test", `
+		/test [from=/test]
+	`)
+	insertEqual(t, tree, "/team", `
+		/te
+		•••st [from=/test]
+		•••am [from=/team]
+	`)
+	insertEqual(t, tree, "/toast", `
+		/t
+		••e
+		•••st [from=/test]
+		•••am [from=/team]
+		••oast [from=/toast]
+	`)
+	deleteEqual(t, tree, "/team", `
+		/t
+		••est [from=/test]
+		••oast [from=/toast]
+	`)
+	deleteEqual(t, tree, "/team", `no match for /team`)
+	deleteEqual(t, tree, "/test/", `
+		/toast [from=/toast]
+	`)
+	deleteEqual(t, tree, "/toast", ``)
+	deleteEqual(t, tree, "/toast", `no match for /toast`)
+}
+
+func TestDeleteSplit(t *testing.T) {
+	tree := enroute.New()
+	insertEqual(t, tree, "/slow", `
+		/slow [from=/slow]
+	`)
+	insertEqual(t, tree, "/slower", `
+		/slow [from=/slow]
+		•••••er [from=/slower]
+	`)
+	insertEqual(t, tree, "/slowest", `
+		/slow [from=/slow]
+		•••••e
+		••••••r [from=/slower]
+		••••••st [from=/slowest]
+	`)
+	deleteEqual(t, tree, "/slow", `
+		/slowe
+		••••••r [from=/slower]
+		••••••st [from=/slowest]
+	`)
+	deleteEqual(t, tree, "/slowest", `
+		/slower [from=/slower]
+	`)
+}
+
+func TestDeleteExpanded(t *testing.T) {
+	tree := enroute.New()
+	insertEqual(t, tree, "/", `
+		/ [from=/]
+	`)
+	insertEqual(t, tree, "/users/{id?}", `
+		/ [from=/]
+		•users [from=/users/{id?}]
+		••••••/{id} [from=/users/{id?}]
+	`)
+	insertEqual(t, tree, "/files/{path*}", `
+		/ [from=/]
+		•users [from=/users/{id?}]
+		••••••/{id} [from=/users/{id?}]
+		•files [from=/files/{path*}]
+		••••••/{path*} [from=/files/{path*}]
+	`)
+	deleteEqual(t, tree, "/users/{id?}", `
+		/ [from=/]
+		•files [from=/files/{path*}]
+		••••••/{path*} [from=/files/{path*}]
+	`)
+	deleteEqual(t, tree, "/files/{path*}", `
+		/ [from=/]
+	`)
+	deleteEqual(t, tree, "/users/{id}", `no match for /users/{id}`)
+}
+
+func TestDeleteKeepsOverlapping(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/", "index"))
+	is.NoErr(tree.Insert("/{name?}", "name"))
+	is.NoErr(tree.Delete("/{name?}"))
+	is.Equal(strings.TrimSpace(tree.String()), "/ [from=/]")
+	match, err := tree.Match("/")
+	is.NoErr(err)
+	is.Equal(match.Value, "index")
+	match, err = tree.Match("/a")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	is.Equal(match, nil)
+}
+
+func TestDeleteRestoresShadowed(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	insertEqual(t, tree, "/{name?}", `
+		/ [from=/{name?}]
+		•{name} [from=/{name?}]
+	`)
+	insertEqual(t, tree, "/", `
+		/ [from=/]
+		•{name} [from=/{name?}]
+	`)
+	deleteEqual(t, tree, "/", `
+		/ [from=/{name?}]
+		•{name} [from=/{name?}]
+	`)
+	match, err := tree.Match("/")
+	is.NoErr(err)
+	is.Equal(match.Route, "/{name?}")
+	// Deleting the shadowed route keeps the route that shadowed it
+	is.NoErr(tree.Insert("/", "index"))
+	is.NoErr(tree.Delete("/{name?}"))
+	is.Equal(strings.TrimSpace(tree.String()), "/ [from=/]")
+	is.NoErr(tree.Delete("/"))
+	is.Equal(tree.String(), "")
+}
+
+func TestDeleteReinsert(t *testing.T) {
+	is := is.New(t)
+	routes := []string{
+		"/",
+		"/users",
+		"/users/{id}",
+		"/users/{id}/edit",
+		"/users/settings",
+		"/posts/{post_id}/comments/{id?}",
+		"/v{major|[0-9]+}.{minor|[0-9]+}",
+		"/{owner}/{repo}/{path*}",
+	}
+	for _, skip := range routes {
+		expected := enroute.New()
+		actual := enroute.New()
+		for _, route := range routes {
+			is.NoErr(actual.Insert(route, route))
+			if route != skip {
+				is.NoErr(expected.Insert(route, route))
+			}
+		}
+		is.NoErr(actual.Delete(skip))
+		diff.TestString(t, actual.String(), expected.String())
+	}
+}
+
 type Routes []Route
 
 type Route struct {
@@ -376,7 +544,7 @@ type Request struct {
 	Expect string
 }
 
-func matchPath(t *testing.T, tree *enroute.Tree, path string, expect string) error {
+func matchPath(t *testing.T, tree *enroute.Tree[string], path string, expect string) error {
 	t.Helper()
 	match, err := tree.Match(path)
 	if err != nil {
@@ -519,7 +687,7 @@ func TestNoRoutes(t *testing.T) {
 	match, err = tree.Match("/a")
 	is.True(errors.Is(err, enroute.ErrNoMatch))
 	is.Equal(match, nil)
-	tree.Each(func(n *enroute.Node) bool {
+	tree.Each(func(n *enroute.Node[string]) bool {
 		is.Fail() // should not be called
 		return true
 	})
@@ -626,6 +794,76 @@ func TestMatchUnicode(t *testing.T) {
 	})
 }
 
+func TestMatchUnicodeSlots(t *testing.T) {
+	matchEqual(t, Routes{
+		{"/café/{name}", Requests{
+			{"/café/bob", `/café/{name} name=bob`},
+			{"/CAFÉ/bob", `/café/{name} name=bob`},
+			{"/caf%C3%A9/bob", `/café/{name} name=bob`},
+		}},
+		{"/cafe/{name}", Requests{
+			{"/cafe/bob", `/cafe/{name} name=bob`},
+		}},
+	})
+	matchEqual(t, Routes{
+		{"/日本/{id}", Requests{
+			{"/日本/10", `/日本/{id} id=10`},
+			{"/日本/東京", `/日本/{id} id=東京`},
+			{"/%E6%97%A5%E6%9C%AC/10", `/日本/{id} id=10`},
+			{"/日/10", `no match for "/日/10"`},
+		}},
+		{"/日曜/{id}", Requests{
+			{"/日曜/10", `/日曜/{id} id=10`},
+		}},
+	})
+	matchEqual(t, Routes{
+		{"/{from}の{to}", Requests{
+			{"/àのé", `/{from}の{to} from=à&to=é`},
+			{"/àéのéà", `/{from}の{to} from=àé&to=éà`},
+		}},
+	})
+	matchEqual(t, Routes{
+		{"/{a}é{b}", Requests{
+			{"/xàyéz", `/{a}é{b} a=xày&b=z`},
+		}},
+	})
+}
+
+func TestMatchUnicodeNormalized(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	// Route written with a decomposed é (e + U+0301)
+	is.NoErr(tree.Insert("/café/{name}", "cafe"))
+	// Composed é
+	match, err := tree.Match("/café/bob")
+	is.NoErr(err)
+	is.Equal(match.Route, "/café/{name}")
+	// Decomposed é
+	match, err = tree.Match("/café/bob")
+	is.NoErr(err)
+	is.Equal(match.Route, "/café/{name}")
+	// Encoded decomposed é
+	match, err = tree.Match("/cafe%CC%81/bob")
+	is.NoErr(err)
+	is.Equal(match.Route, "/café/{name}")
+	// Slot values are normalized too
+	match, err = tree.Match("/cafe/josé")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	match, err = tree.Match("/café/jose%CC%81")
+	is.NoErr(err)
+	is.Equal(match.Slots[0].Value, "josé")
+	// Raw values are cut from the path before it was normalized
+	is.Equal(match.Slots[0].Raw, "jose%CC%81")
+	match, err = tree.Match("/caf%C3%A9/jos%C3%A9%2F1")
+	is.NoErr(err)
+	is.Equal(match.Slots[0].Value, "josé/1")
+	is.Equal(match.Slots[0].Raw, "jos%C3%A9%2F1")
+	match, err = tree.Match("/cafe%CC%81/jose\u0301")
+	is.NoErr(err)
+	is.Equal(match.Slots[0].Value, "jos\u00e9")
+	is.Equal(match.Slots[0].Raw, "jose\u0301")
+}
+
 func TestOptional(t *testing.T) {
 	matchEqual(t, Routes{
 		{"/{id?}", Requests{
@@ -825,6 +1063,303 @@ func TestMatchRegexp(t *testing.T) {
 	})
 }
 
+func TestInsertTyped(t *testing.T) {
+	tree := enroute.New()
+	insertEqual(t, tree, "/users/{id:int}", `
+		/users/{id:int} [from=/users/{id:int}]
+	`)
+	insertEqual(t, tree, "/users/{name}", `
+		/users/
+		•••••••{id:int} [from=/users/{id:int}]
+		•••••••{name} [from=/users/{name}]
+	`)
+	insertEqual(t, tree, "/users/{slug|[a-z]+}", `
+		/users/
+		•••••••{id:int} [from=/users/{id:int}]
+		•••••••{slug|^[a-z]+$} [from=/users/{slug|^[a-z]+$}]
+		•••••••{name} [from=/users/{name}]
+	`)
+	insertEqual(t, tree, "/users/new", `
+		/users/
+		•••••••new [from=/users/new]
+		•••••••{id:int} [from=/users/{id:int}]
+		•••••••{slug|^[a-z]+$} [from=/users/{slug|^[a-z]+$}]
+		•••••••{name} [from=/users/{name}]
+	`)
+	insertEqual(t, tree, "/users/{key:int}", `route "/users/{key:int}" is ambiguous with "/users/{id:int}"`)
+	insertEqual(t, tree, "/users/{id:uuid}", `
+		/users/
+		•••••••new [from=/users/new]
+		•••••••{id:int} [from=/users/{id:int}]
+		•••••••{slug|^[a-z]+$} [from=/users/{slug|^[a-z]+$}]
+		•••••••{id:uuid} [from=/users/{id:uuid}]
+		•••••••{name} [from=/users/{name}]
+	`)
+	insertEqual(t, tree, "/users/{id:float}", `unknown type "float" in slot "id"`)
+}
+
+func TestMatchTyped(t *testing.T) {
+	matchEqual(t, Routes{
+		{"/users/{id:int}", Requests{
+			{"/users/10", `/users/{id:int} id=10`},
+			{"/users/-1", `/users/{id:int} id=-1`},
+		}},
+		{"/users/{id:uuid}", Requests{
+			{"/users/123e4567-e89b-12d3-a456-426614174000", `/users/{id:uuid} id=123e4567-e89b-12d3-a456-426614174000`},
+		}},
+		{"/users/{name}", Requests{
+			{"/users/alice", `/users/{name} name=alice`},
+			{"/users/10a", `/users/{name} name=10a`},
+		}},
+		{"/users/new", Requests{
+			{"/users/new", `/users/new`},
+		}},
+		{"/archive/{day:date}.{format}", Requests{
+			{"/archive/2024-02-29.json", `/archive/{day:date}.{format} day=2024-02-29&format=json`},
+			{"/archive/2023-02-29.json", `no match for "/archive/2023-02-29.json"`},
+		}},
+	})
+}
+
+func TestMatchTypedParsed(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/posts/{id:int}/{day:date}/{slug}", "post"))
+	match, err := tree.Match("/posts/10/2024-02-29/hello")
+	is.NoErr(err)
+	is.Equal(len(match.Slots), 3)
+	is.Equal(match.Slots[0].Value, "10")
+	is.Equal(match.Slots[0].Parsed, 10)
+	is.Equal(match.Slots[1].Value, "2024-02-29")
+	is.Equal(match.Slots[1].Parsed, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC))
+	is.Equal(match.Slots[2].Value, "hello")
+	is.Equal(match.Slots[2].Parsed, nil)
+}
+
+func TestRegisterType(t *testing.T) {
+	is := is.New(t)
+	// Types are global, so the name shouldn't clash with other tests
+	parses := 0
+	enroute.RegisterType("test_color", func(value string) (any, error) {
+		parses++
+		switch value {
+		case "red", "green", "blue":
+			return strings.ToUpper(value), nil
+		default:
+			return nil, fmt.Errorf("unknown color %q", value)
+		}
+	})
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/paint/{color:test_color}", "paint"))
+	is.NoErr(tree.Insert("/paint/{name}", "name"))
+	match, err := tree.Match("/paint/red")
+	is.NoErr(err)
+	is.Equal(match.Value, "paint")
+	is.Equal(match.Slots[0].Parsed, "RED")
+	// The value is only parsed while matching
+	is.Equal(parses, 1)
+	match, err = tree.Match("/paint/pink")
+	is.NoErr(err)
+	is.Equal(match.Value, "name")
+}
+
+func TestMatchSlots(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/posts/{post_id}/comments/{id}", "comment"))
+	is.NoErr(tree.Insert("/users/{id:int}", "user"))
+	match, err := tree.Match("/posts/10/comments/abc")
+	is.NoErr(err)
+	value, ok := match.Get("post_id")
+	is.True(ok)
+	is.Equal(value, "10")
+	value, ok = match.Get("id")
+	is.True(ok)
+	is.Equal(value, "abc")
+	value, ok = match.Get("name")
+	is.True(!ok)
+	is.Equal(value, "")
+	is.Equal(match.Map(), map[string]string{"post_id": "10", "id": "abc"})
+	n, err := match.Int("post_id")
+	is.NoErr(err)
+	is.Equal(n, 10)
+	n, err = match.Int("id")
+	is.Equal(err.Error(), `invalid value "abc" for slot "id"`)
+	is.Equal(n, 0)
+	n, err = match.Int("name")
+	is.Equal(err.Error(), `missing value for slot "name"`)
+	is.Equal(n, 0)
+	var missing *ast.ErrMissingSlot
+	is.True(errors.As(err, &missing))
+	is.Equal(missing.Slot, "name")
+	match, err = tree.Match("/users/42")
+	is.NoErr(err)
+	n, err = match.Int("id")
+	is.NoErr(err)
+	is.Equal(n, 42)
+	is.Equal(match.Map(), map[string]string{"id": "42"})
+}
+
+func TestMatchEncoded(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/users/{id}", "users/show"))
+	is.NoErr(tree.Insert("/posts/{id|[0-9]+}", "posts/show"))
+	is.NoErr(tree.Insert("/files/{path*}", "files/show"))
+	is.NoErr(tree.Insert("/v.{version:int}", "version"))
+	match, err := tree.Match("/users/hello%20world")
+	is.NoErr(err)
+	is.Equal(match.Route, "/users/{id}")
+	is.Equal(match.Slots[0].Value, "hello world")
+	is.Equal(match.Slots[0].Raw, "hello%20world")
+	// Literals are compared after decoding
+	match, err = tree.Match("/%75sers/10")
+	is.NoErr(err)
+	is.Equal(match.Route, "/users/{id}")
+	is.Equal(match.Slots[0].Value, "10")
+	// Encoded slashes don't separate segments
+	match, err = tree.Match("/users/a%2Fb")
+	is.NoErr(err)
+	is.Equal(match.Route, "/users/{id}")
+	is.Equal(match.Slots[0].Value, "a/b")
+	is.Equal(match.Slots[0].Raw, "a%2Fb")
+	// Regexp and typed slots check the decoded value
+	match, err = tree.Match("/posts/%31%32")
+	is.NoErr(err)
+	is.Equal(match.Slots[0].Value, "12")
+	match, err = tree.Match("/v.%34%32")
+	is.NoErr(err)
+	is.Equal(match.Slots[0].Parsed, 42)
+	match, err = tree.Match("/files/a%20b/c")
+	is.NoErr(err)
+	is.Equal(match.Slots[0].Value, "a b/c")
+	// Invalid encodings don't match
+	_, err = tree.Match("/users/%zz")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	_, err = tree.Match("/%7")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+}
+
+func TestEncodedSlash(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New(enroute.WithEncodedSlash(enroute.KeepEncodedSlash))
+	is.NoErr(tree.Insert("/users/{id}", "users/show"))
+	match, err := tree.Match("/users/a%2fb%20c")
+	is.NoErr(err)
+	is.Equal(match.Slots[0].Value, "a%2Fb c")
+	is.Equal(match.Slots[0].Raw, "a%2fb%20c")
+	tree = enroute.New(enroute.WithEncodedSlash(enroute.RejectEncodedSlash))
+	is.NoErr(tree.Insert("/users/{id}", "users/show"))
+	is.NoErr(tree.Insert("/users/{id}/{path*}", "users/files"))
+	match, err = tree.Match("/users/a%20b")
+	is.NoErr(err)
+	is.Equal(match.Route, "/users/{id}")
+	_, err = tree.Match("/users/a%2Fb")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	_, err = tree.Match("/users/10/a%2Fb")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	match, err = tree.Match("/users/10/a/b")
+	is.NoErr(err)
+	is.Equal(match.Route, "/users/{id}/{path*}")
+}
+
+func TestDecodedSlotURL(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.InsertNamed("users.show", "/users/{id}", "users/show"))
+	is.NoErr(tree.InsertNamed("files.show", "/files/{path*}", "files/show"))
+	paths := []string{
+		"/users/a%2Fb",
+		"/users/a%3Fb%25c",
+		"/users/a%20b",
+		"/files/a%20b/c%3Fd/e%25",
+	}
+	for _, path := range paths {
+		match, err := tree.Match(path)
+		is.NoErr(err)
+		name := "users.show"
+		if match.Route == "/files/{path*}" {
+			name = "files.show"
+		}
+		// Building from the decoded slots gives back the same path
+		url, err := tree.URL(name, match.Slots...)
+		is.NoErr(err)
+		is.Equal(url, path)
+		again, err := tree.Match(url)
+		is.NoErr(err)
+		is.Equal(again.Slots[0].Value, match.Slots[0].Value)
+	}
+	// Kept encoded slashes come back as the same value
+	tree = enroute.New(enroute.WithEncodedSlash(enroute.KeepEncodedSlash))
+	is.NoErr(tree.InsertNamed("users.show", "/users/{id}", "users/show"))
+	match, err := tree.Match("/users/a%2Fb%20c")
+	is.NoErr(err)
+	url, err := tree.URL("users.show", match.Slots...)
+	is.NoErr(err)
+	again, err := tree.Match(url)
+	is.NoErr(err)
+	is.Equal(again.Slots[0].Value, "a%2Fb c")
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.True(tree.Insert("/API/docs", "docs") != nil)
+	is.NoErr(tree.Insert("/api/docs", "docs"))
+	match, err := tree.Match("/API/Docs")
+	is.NoErr(err)
+	is.Equal(match.Route, "/api/docs")
+	is.Equal(match.Redirect, "")
+}
+
+func TestCaseSensitive(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New(enroute.WithCase(enroute.CaseSensitive))
+	is.NoErr(tree.Insert("/API/Docs", "upper"))
+	is.NoErr(tree.Insert("/api/docs", "lower"))
+	is.NoErr(tree.Insert("/Users/{id}", "users"))
+	match, err := tree.Match("/API/Docs")
+	is.NoErr(err)
+	is.Equal(match.Value, "upper")
+	match, err = tree.Match("/api/docs")
+	is.NoErr(err)
+	is.Equal(match.Value, "lower")
+	_, err = tree.Match("/Api/Docs")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	match, err = tree.Match("/Users/AbC")
+	is.NoErr(err)
+	is.Equal(match.Slots[0].Value, "AbC")
+	_, err = tree.Match("/users/abc")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	// Clones keep the case mode
+	match, err = tree.Clone().Match("/API/Docs")
+	is.NoErr(err)
+	is.Equal(match.Value, "upper")
+}
+
+func TestCaseRedirect(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New(enroute.WithCase(enroute.CaseRedirect))
+	is.NoErr(tree.Insert("/API/Docs", "docs"))
+	is.NoErr(tree.Insert("/API/Users/{id}/{path*}", "users"))
+	is.NoErr(tree.Insert("/about", "about"))
+	err := tree.Insert("/api/docs", "docs")
+	is.True(errors.Is(err, enroute.ErrDuplicate))
+	match, err := tree.Match("/API/Docs")
+	is.NoErr(err)
+	is.Equal(match.Redirect, "")
+	match, err = tree.Match("/api/docs/")
+	is.NoErr(err)
+	is.Equal(match.Route, "/API/Docs")
+	is.Equal(match.Redirect, "/API/Docs")
+	match, err = tree.Match("/api/users/Ab/Some/File")
+	is.NoErr(err)
+	is.Equal(match.Redirect, "/API/Users/Ab/Some/File")
+	match, err = tree.Match("/ABOUT")
+	is.NoErr(err)
+	is.Equal(match.Redirect, "/about")
+}
+
 func TestResource(t *testing.T) {
 	tree := enroute.New()
 	insertEqual(t, tree, "/{id}/edit", `
@@ -990,6 +1525,45 @@ func ExampleMatch() {
 	// path internal/parser/parser.go
 }
 
+func TestGenericValues(t *testing.T) {
+	is := is.New(t)
+	type handler func(slots []*enroute.Slot) string
+	tree := enroute.NewTree[handler]()
+	is.NoErr(tree.Insert("/", func([]*enroute.Slot) string { return "index" }))
+	is.NoErr(tree.Insert("/users/{id}", func(slots []*enroute.Slot) string {
+		return "user " + slots[0].Value
+	}))
+	match, err := tree.Match("/users/10")
+	is.NoErr(err)
+	is.Equal(match.Route, "/users/{id}")
+	is.Equal(match.Value(match.Slots), "user 10")
+	match, err = tree.Match("/")
+	is.NoErr(err)
+	is.Equal(match.Value(match.Slots), "index")
+	match, err = tree.Match("/posts")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	is.Equal(match, nil)
+	node, err := tree.Find("/users/{id}")
+	is.NoErr(err)
+	is.Equal(node.Value([]*enroute.Slot{{Key: "id", Value: "20"}}), "user 20")
+	is.NoErr(tree.Delete("/users/{id}"))
+	_, err = tree.Find("/users/{id}")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+}
+
+func TestGenericZeroValue(t *testing.T) {
+	is := is.New(t)
+	tree := new(enroute.Tree[int])
+	_, err := tree.Match("/")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	is.NoErr(tree.Insert("/v{major|[0-9]+}", 1))
+	is.NoErr(tree.Insert("/v{major|[0-9]+}.{minor|[0-9]+}", 2))
+	match, err := tree.Match("/v1.2")
+	is.NoErr(err)
+	is.Equal(match.Value, 2)
+	is.Equal(match.String(), "/v{major|^[0-9]+$}.{minor|^[0-9]+$} major=1&minor=2")
+}
+
 func TestParse(t *testing.T) {
 	is := is.New(t)
 	route, err := enroute.Parse("/posts/{post_id}/comments/{id}")
@@ -1001,6 +1575,266 @@ func TestParse(t *testing.T) {
 	is.Equal(route, nil)
 }
 
+func TestBuild(t *testing.T) {
+	is := is.New(t)
+	path, err := enroute.Build("/posts/{post_id}/comments/{id?}/", map[string]string{"post_id": "1"})
+	is.NoErr(err)
+	is.Equal(path, "/posts/1/comments")
+	path, err = enroute.Build("/posts/{post_id}/comments/{id?}/", map[string]string{"post_id": "1", "id": "2"})
+	is.NoErr(err)
+	is.Equal(path, "/posts/1/comments/2")
+	path, err = enroute.Build("/posts/{post_id}", nil)
+	is.Equal(err.Error(), `missing value for slot "post_id"`)
+	is.Equal(path, "")
+	path, err = enroute.Build("posts/{post_id}", nil)
+	is.Equal(err.Error(), "path must start with a slash /")
+	is.Equal(path, "")
+}
+
+func TestBuildMatches(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	routes := []string{
+		"/users/{id}/edit",
+		"/fly/{from}-{to}",
+		"/v{major|[0-9]+}.{minor|[0-9]+}",
+		"/{owner}/{repo}/{branch}/{path*}",
+	}
+	for _, route := range routes {
+		is.NoErr(tree.Insert(route, route))
+	}
+	values := map[string]string{
+		"id":     "10",
+		"from":   "sfo",
+		"to":     "ber",
+		"major":  "1",
+		"minor":  "2",
+		"owner":  "matthewmueller",
+		"repo":   "enroute",
+		"branch": "main",
+		"path":   "internal/parser/parser.go",
+	}
+	for _, route := range routes {
+		path, err := enroute.Build(route, values)
+		is.NoErr(err)
+		match, err := tree.Match(path)
+		is.NoErr(err)
+		is.Equal(match.Value, route)
+		for _, slot := range match.Slots {
+			is.Equal(slot.Value, values[slot.Key])
+		}
+	}
+}
+
+func TestBuildEscapes(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	tree.MustInsert("/users/{id}", "users")
+	tree.MustInsert("/files/{path*}", "files")
+	tests := []struct {
+		route  string
+		values map[string]string
+		path   string
+	}{
+		{"/users/{id}", map[string]string{"id": "a?b"}, "/users/a%3Fb"},
+		{"/users/{id}", map[string]string{"id": "a b%"}, "/users/a%20b%25"},
+		{"/users/{id}", map[string]string{"id": "a#b"}, "/users/a%23b"},
+		{"/users/{id}", map[string]string{"id": "café"}, "/users/caf%C3%A9"},
+		{"/files/{path*}", map[string]string{"path": "a b/c?d/e%f"}, "/files/a%20b/c%3Fd/e%25f"},
+	}
+	for _, test := range tests {
+		path, err := enroute.Build(test.route, test.values)
+		is.NoErr(err)
+		is.Equal(path, test.path)
+		match, err := tree.Match(path)
+		is.NoErr(err)
+		is.Equal(match.Route, test.route)
+		for _, slot := range match.Slots {
+			is.Equal(slot.Value, test.values[slot.Key]) // value matches back the same
+		}
+	}
+}
+
+func TestInsertNamed(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.InsertNamed("index", "/", "index.html"))
+	is.NoErr(tree.InsertNamed("users.show", "/users/{id}", "users/show.html"))
+	is.NoErr(tree.InsertNamed("posts.comments", "/posts/{post_id}/comments/{id?}", "posts/comments.html"))
+	is.NoErr(tree.InsertNamed("repo", "/{owner}/{repo}/{path*}", "repo.html"))
+	url, err := tree.URL("index")
+	is.NoErr(err)
+	is.Equal(url, "/")
+	url, err = tree.URL("users.show", &enroute.Slot{Key: "id", Value: "10"})
+	is.NoErr(err)
+	is.Equal(url, "/users/10")
+	url, err = tree.URL("posts.comments", &enroute.Slot{Key: "post_id", Value: "1"})
+	is.NoErr(err)
+	is.Equal(url, "/posts/1/comments")
+	url, err = tree.URL("posts.comments", &enroute.Slot{Key: "post_id", Value: "1"}, &enroute.Slot{Key: "id", Value: "2"})
+	is.NoErr(err)
+	is.Equal(url, "/posts/1/comments/2")
+	match, err := tree.Match("/matthewmueller/enroute/internal/parser/parser.go")
+	is.NoErr(err)
+	url, err = tree.URL("repo", match.Slots...)
+	is.NoErr(err)
+	is.Equal(url, "/matthewmueller/enroute/internal/parser/parser.go")
+	url, err = tree.URL("users.show")
+	is.Equal(err.Error(), `missing value for slot "id"`)
+	is.Equal(url, "")
+	url, err = tree.URL("users.edit")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	is.Equal(err.Error(), `no match for name "users.edit"`)
+	is.Equal(url, "")
+}
+
+func TestInsertNamedDuplicate(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.InsertNamed("users.show", "/users/{id}", "users/show.html"))
+	err := tree.InsertNamed("users.show", "/users/{id}/edit", "users/edit.html")
+	is.True(errors.Is(err, enroute.ErrDuplicate))
+	is.Equal(err.Error(), `route name already exists "users.show"`)
+	_, err = tree.Find("/users/{id}/edit")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	err = tree.InsertNamed("users.edit", "/users/{id}", "users/edit.html")
+	is.True(errors.Is(err, enroute.ErrDuplicate))
+	_, err = tree.URL("users.edit")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	// Deleting the route frees up the name
+	is.NoErr(tree.Delete("/users/{id}"))
+	_, err = tree.URL("users.show")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	is.NoErr(tree.InsertNamed("users.show", "/users/{id}", "users/show.html"))
+}
+
+func TestInsertMethod(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.InsertMethod("GET", "/users", "users/index"))
+	is.NoErr(tree.InsertMethod("post", "/users", "users/create"))
+	is.NoErr(tree.InsertMethod("GET", "/users/{id}", "users/show"))
+	is.NoErr(tree.InsertMethod("PATCH", "/users/{id}", "users/update"))
+	is.NoErr(tree.InsertMethod("DELETE", "/users/{id}", "users/delete"))
+	is.NoErr(tree.Insert("/about", "about"))
+	is.Equal(strings.TrimSpace(tree.String()), strings.TrimSpace(`
+/
+•users [from=/users, methods=GET|POST]
+••••••/{id} [from=/users/{id}, methods=DELETE|GET|PATCH]
+•about [from=/about]
+	`))
+	match, err := tree.MatchMethod("GET", "/users")
+	is.NoErr(err)
+	is.Equal(match.Value, "users/index")
+	match, err = tree.MatchMethod("POST", "/users/")
+	is.NoErr(err)
+	is.Equal(match.Value, "users/create")
+	match, err = tree.MatchMethod("patch", "/users/10")
+	is.NoErr(err)
+	is.Equal(match.String(), "/users/{id} id=10")
+	is.Equal(match.Value, "users/update")
+	// Routes without a method handle every method
+	match, err = tree.MatchMethod("DELETE", "/about")
+	is.NoErr(err)
+	is.Equal(match.Value, "about")
+	// Method not allowed
+	match, err = tree.MatchMethod("PUT", "/users/10")
+	is.Equal(match, nil)
+	var notAllowed *enroute.ErrMethodNotAllowed
+	is.True(errors.As(err, &notAllowed))
+	is.Equal(notAllowed.Method, "PUT")
+	is.Equal(notAllowed.Path, "/users/10")
+	is.Equal(notAllowed.Allowed, []string{"DELETE", "GET", "PATCH"})
+	is.Equal(err.Error(), `method PUT not allowed for "/users/10", allowed DELETE, GET, PATCH`)
+	// No match
+	match, err = tree.MatchMethod("GET", "/posts")
+	is.Equal(match, nil)
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	is.True(!errors.As(err, &notAllowed))
+}
+
+func TestInsertMethodDuplicate(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.InsertMethod("GET", "/users/{id?}", "users/show"))
+	is.NoErr(tree.InsertMethod("POST", "/users/{id?}", "users/create"))
+	err := tree.InsertMethod("GET", "/users/{id?}", "users/show")
+	is.True(errors.Is(err, enroute.ErrDuplicate))
+	is.Equal(err.Error(), `route already exists GET "/users/{id?}"`)
+	err = tree.Insert("/users/{id?}", "users")
+	is.True(errors.Is(err, enroute.ErrDuplicate))
+	err = tree.InsertMethod("PUT", "/users/{name?}", "users")
+	is.True(errors.Is(err, enroute.ErrDuplicate))
+	err = tree.InsertMethod("", "/users/{id}", "users")
+	is.Equal(err.Error(), `enroute: method can't be empty for "/users/{id}"`)
+	match, err := tree.MatchMethod("POST", "/users")
+	is.NoErr(err)
+	is.Equal(match.Value, "users/create")
+	match, err = tree.MatchMethod("GET", "/users/10")
+	is.NoErr(err)
+	is.Equal(match.Value, "users/show")
+	is.NoErr(tree.Delete("/users/{id?}"))
+	_, err = tree.MatchMethod("GET", "/users/10")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+}
+
+func TestInsertMethodShadowed(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.InsertMethod("GET", "/users/{id}", "users/show"))
+	is.NoErr(tree.InsertMethod("POST", "/users/{id?}", "users/create"))
+	is.Equal(strings.TrimSpace(tree.String()), strings.TrimSpace(`
+/users [from=/users/{id?}, methods=POST]
+••••••/{id} [from=/users/{id}, methods=GET|POST]
+	`))
+	match, err := tree.MatchMethod("POST", "/users/10")
+	is.NoErr(err)
+	is.Equal(match.Value, "users/create")
+	match, err = tree.MatchMethod("GET", "/users/10")
+	is.NoErr(err)
+	is.Equal(match.Value, "users/show")
+	// Methods that collide are duplicates
+	err = tree.InsertMethod("GET", "/users/{name?}", "users/name")
+	is.True(errors.Is(err, enroute.ErrDuplicate))
+	err = tree.InsertMethod("POST", "/users/{id}", "users/update")
+	is.True(errors.Is(err, enroute.ErrDuplicate))
+	is.Equal(err.Error(), `route POST "/users/{id}" is ambiguous with "/users/{id?}"`)
+	// Deleting either route keeps the other's methods
+	is.NoErr(tree.Delete("/users/{id?}"))
+	_, err = tree.MatchMethod("POST", "/users/10")
+	var notAllowed *enroute.ErrMethodNotAllowed
+	is.True(errors.As(err, &notAllowed))
+	is.Equal(notAllowed.Allowed, []string{"GET"})
+	is.NoErr(tree.InsertMethod("POST", "/users/{id?}", "users/create"))
+	is.NoErr(tree.Delete("/users/{id}"))
+	match, err = tree.MatchMethod("POST", "/users/10")
+	is.NoErr(err)
+	is.Equal(match.Route, "/users/{id?}")
+	_, err = tree.MatchMethod("GET", "/users/10")
+	is.True(errors.As(err, &notAllowed))
+	is.Equal(notAllowed.Allowed, []string{"POST"})
+}
+
+func TestMatchMethodFallthrough(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.InsertMethod("GET", "/users/new", "users/new"))
+	is.NoErr(tree.InsertMethod("POST", "/users/{id}", "users/update"))
+	is.NoErr(tree.InsertMethod("PUT", "/users/{id}", "users/update"))
+	match, err := tree.MatchMethod("POST", "/users/new")
+	is.NoErr(err)
+	is.Equal(match.String(), "/users/{id} id=new")
+	is.Equal(match.Value, "users/update")
+	_, err = tree.MatchMethod("DELETE", "/users/new")
+	var notAllowed *enroute.ErrMethodNotAllowed
+	is.True(errors.As(err, &notAllowed))
+	is.Equal(notAllowed.Allowed, []string{"GET", "POST", "PUT"})
+	node, err := tree.Find("/users/{id}")
+	is.NoErr(err)
+	is.Equal(node.Methods(), []string{"POST", "PUT"})
+	is.Equal(node.Value, "")
+}
+
 func TestMultipleSlots(t *testing.T) {
 	tree := enroute.New()
 	insertEqual(t, tree, "/border-spacing-{number}", `
@@ -0,0 +1,219 @@
+package enroute
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/matthewmueller/enroute/ast"
+)
+
+// Explanation of how a path was matched against the tree
+type Explanation struct {
+	Path  string  // Path that was matched
+	Route string  // Route that matched, empty if nothing matched
+	Steps []*Step // Nodes that were tried in order
+}
+
+// Step is a node in the tree that was tried while matching
+type Step struct {
+	Depth   int    // Depth of the node in the tree
+	Node    string // Sections of the node
+	Route   string // Route of the node, empty for splits in the tree
+	Input   string // Input that was left when the node was tried
+	Matched int    // Number of characters the node matched
+	Section string // Section that rejected the input, if any
+	Reason  string // Reason the node rejected the input, empty if it didn't
+}
+
+func (s *Step) String() string {
+	out := fmt.Sprintf("%s matched %d of %q", s.Node, s.Matched, s.Input)
+	if s.Route != "" {
+		out += " [from=" + s.Route + "]"
+	}
+	if s.Section != "" {
+		out += ", rejected by " + s.Section
+	}
+	if s.Reason != "" {
+		out += ": " + s.Reason
+	}
+	return out
+}
+
+func (e *Explanation) String() string {
+	s := new(strings.Builder)
+	for _, step := range e.Steps {
+		s.WriteString(strings.Repeat("  ", step.Depth))
+		s.WriteString(step.String())
+		s.WriteString("\n")
+	}
+	if e.Route == "" {
+		fmt.Fprintf(s, "no match for %q\n", e.Path)
+	} else {
+		fmt.Fprintf(s, "%q matched %s\n", e.Path, e.Route)
+	}
+	return s.String()
+}
+
+// Explain walks the tree like Match does, recording each node it tried and why
+// the node rejected the path. This is useful for debugging routes that don't
+// match. Paths with a host are explained within the routes of each host that
+// matches, then within the routes without a host. Slots are checked against
+// the tree's encoded slash policy and routes with query parameters against
+// the query.
+func (t *Tree[V]) Explain(input string) *Explanation {
+	return t.explain(new(matcher), input)
+}
+
+// ExplainMethod explains the path like MatchMethod does, also rejecting routes
+// that don't handle the method
+func (t *Tree[V]) ExplainMethod(method string, input string) *Explanation {
+	return t.explain(&matcher{method: strings.ToUpper(method)}, input)
+}
+
+func (t *Tree[V]) explain(m *matcher, input string) *Explanation {
+	input, host, path := t.prepare(m, input)
+	e := &Explanation{Path: input}
+	if len(path) == 0 || path[0] != '/' {
+		return e
+	}
+	var trees []*Tree[V]
+	for _, h := range t.matchHost(host) {
+		trees = append(trees, t.trees[h.node.Label])
+	}
+	for _, tree := range append(trees, t) {
+		if tree.root == nil {
+			continue
+		}
+		if node, ok := tree.root.explain(m, e, 0, path); ok {
+			e.Route = node.Label
+			return e
+		}
+	}
+	return e
+}
+
+func (n *Node[V]) explain(m *matcher, e *Explanation, depth int, path string) (*Node[V], bool) {
+	step := &Step{
+		Depth: depth,
+		Node:  n.sections.String(),
+		Route: n.Label,
+		Input: path,
+	}
+	e.Steps = append(e.Steps, step)
+	for _, section := range n.sections {
+		if len(path) == 0 {
+			step.Section = section.String()
+			step.Reason = "input ended"
+			return nil, false
+		}
+		index, slot, ok := matchSection(section, path)
+		if index <= 0 {
+			step.Section = section.String()
+			step.Reason = rejection(section, path)
+			return nil, false
+		}
+		if ok && !m.accept(slot.normalized) {
+			step.Section = section.String()
+			step.Reason = encodingRejection(m, slot.normalized)
+			return nil, false
+		}
+		path = path[index:]
+		step.Matched += index
+	}
+	if len(path) == 0 {
+		if n.Label == "" {
+			step.Reason = "no route ends here"
+			return nil, false
+		}
+		for _, candidate := range n.candidates() {
+			if candidate.handles(m) {
+				return candidate, true
+			}
+		}
+		step.Reason = n.refusal(m)
+		return nil, false
+	}
+	for _, child := range n.children {
+		if node, ok := child.explain(m, e, depth+1, path); ok {
+			return node, true
+		}
+	}
+	if len(n.children) == 0 {
+		step.Reason = fmt.Sprintf("%q is left over", path)
+	} else {
+		step.Reason = fmt.Sprintf("no children matched %q", path)
+	}
+	return nil, false
+}
+
+// refusal explains why none of the node's routes handled the request
+func (n *Node[V]) refusal(m *matcher) string {
+	var allowed []string
+	for _, candidate := range n.candidates() {
+		if m.acceptQuery(candidate.route) {
+			allowed = append(allowed, slices.Collect(maps.Keys(candidate.methods))...)
+		}
+	}
+	if len(allowed) == 0 {
+		return "query doesn't match"
+	}
+	slices.Sort(allowed)
+	return fmt.Sprintf("method %s isn't allowed, allowed %s", m.method, strings.Join(slices.Compact(allowed), ", "))
+}
+
+// encodingRejection explains why the slot's raw value wasn't accepted
+func encodingRejection(m *matcher, raw string) string {
+	if m.encodedSlash == RejectEncodedSlash && indexEncodedSlash(raw) >= 0 {
+		return fmt.Sprintf("%q has an encoded slash", raw)
+	}
+	return fmt.Sprintf("%q isn't properly encoded", raw)
+}
+
+// rejection explains why the section rejected the path
+func rejection(section ast.Section, path string) string {
+	switch s := section.(type) {
+	case *ast.Slash:
+		return fmt.Sprintf("expected \"/\" but got %q", firstRune(path))
+	case *ast.Path:
+		actual := path
+		if len(actual) > len(s.Value) {
+			actual = actual[:len(s.Value)]
+		}
+		return fmt.Sprintf("expected %q but got %q", s.Value, actual)
+	case *ast.RegexpSlot:
+		value := untilDelimiter(s.Delimiters, path)
+		if value == "" {
+			return fmt.Sprintf("slot is empty before the delimiter %q", firstRune(path))
+		}
+		return fmt.Sprintf("%q doesn't match %s", value, s.Pattern)
+	case *ast.TypedSlot:
+		value := untilDelimiter(s.Delimiters, path)
+		if value == "" {
+			return fmt.Sprintf("slot is empty before the delimiter %q", firstRune(path))
+		}
+		if _, err := s.Type.Parse(value); err != nil {
+			return fmt.Sprintf("%q isn't a valid %s: %s", value, s.TypeName, err)
+		}
+	case *ast.RequiredSlot:
+		return fmt.Sprintf("slot is empty before the delimiter %q", firstRune(path))
+	}
+	return fmt.Sprintf("%q doesn't match", path)
+}
+
+func untilDelimiter(delimiters map[rune]bool, path string) string {
+	for i, r := range path {
+		if delimiters[r] {
+			return path[:i]
+		}
+	}
+	return path
+}
+
+func firstRune(path string) string {
+	for _, r := range path {
+		return string(r)
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package enroute_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/matthewmueller/diff"
+	"github.com/matthewmueller/enroute"
+)
+
+func explainEqual(t *testing.T, tree *enroute.Tree[string], path string, expected string) {
+	t.Helper()
+	t.Run(path, func(t *testing.T) {
+		t.Helper()
+		actual := strings.TrimSpace(tree.Explain(path).String())
+		expected = strings.ReplaceAll(strings.TrimSpace(expected), "\t", "")
+		diff.TestString(t, actual, expected)
+	})
+}
+
+func TestExplain(t *testing.T) {
+	tree := enroute.New()
+	tree.MustInsert("/users/{id|[0-9]+}/edit", "users/edit")
+	tree.MustInsert("/users/{name}.{format}", "users/format")
+	tree.MustInsert("/posts/{id:int}", "posts/show")
+	explainEqual(t, tree, "/users/10/edit", `
+		/ matched 1 of "/users/10/edit"
+		  users/ matched 6 of "users/10/edit"
+		    {id|^[0-9]+$}/edit matched 7 of "10/edit" [from=/users/{id|^[0-9]+$}/edit]
+		"/users/10/edit" matched /users/{id|^[0-9]+$}/edit
+	`)
+	explainEqual(t, tree, "/posts/abc", `
+		/ matched 1 of "/posts/abc": no children matched "posts/abc"
+		  users/ matched 0 of "posts/abc", rejected by users: expected "users" but got "posts"
+		  posts/{id:int} matched 6 of "posts/abc" [from=/posts/{id:int}], rejected by {id:int}: "abc" isn't a valid int: strconv.Atoi: parsing "abc": invalid syntax
+		no match for "/posts/abc"
+	`)
+	explainEqual(t, tree, "/users/10/edit/more", `
+		/ matched 1 of "/users/10/edit/more": no children matched "users/10/edit/more"
+		  users/ matched 6 of "users/10/edit/more": no children matched "10/edit/more"
+		    {id|^[0-9]+$}/edit matched 7 of "10/edit/more" [from=/users/{id|^[0-9]+$}/edit]: "/more" is left over
+		    {name}.{format} matched 2 of "10/edit/more" [from=/users/{name}.{format}], rejected by .: expected "." but got "/"
+		  posts/{id:int} matched 0 of "users/10/edit/more" [from=/posts/{id:int}], rejected by posts: expected "posts" but got "users"
+		no match for "/users/10/edit/more"
+	`)
+	explainEqual(t, tree, "/users/ab/edit", `
+		/ matched 1 of "/users/ab/edit": no children matched "users/ab/edit"
+		  users/ matched 6 of "users/ab/edit": no children matched "ab/edit"
+		    {id|^[0-9]+$}/edit matched 0 of "ab/edit" [from=/users/{id|^[0-9]+$}/edit], rejected by {id|^[0-9]+$}: "ab" doesn't match ^[0-9]+$
+		    {name}.{format} matched 2 of "ab/edit" [from=/users/{name}.{format}], rejected by .: expected "." but got "/"
+		  posts/{id:int} matched 0 of "users/ab/edit" [from=/posts/{id:int}], rejected by posts: expected "posts" but got "users"
+		no match for "/users/ab/edit"
+	`)
+}
+
+func TestExplainNoRoutes(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	explanation := tree.Explain("/users")
+	is.Equal(explanation.Route, "")
+	is.Equal(len(explanation.Steps), 0)
+	tree.MustInsert("/users/{id}", "users/show")
+	explanation = tree.Explain("/users")
+	is.Equal(explanation.Route, "")
+	is.Equal(len(explanation.Steps), 1)
+	is.Equal(explanation.Steps[0].Section, "/")
+	is.Equal(explanation.Steps[0].Reason, "input ended")
+}
+
+func TestExplainChecks(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New(enroute.WithEncodedSlash(enroute.RejectEncodedSlash))
+	is.NoErr(tree.InsertMethod("GET", "/users/{id}", "users/show"))
+	tree.MustInsert("/search?q={query}", "search")
+	explainEqual(t, tree, "/users/a%2Fb", `
+		/ matched 1 of "/users/a%2Fb": no children matched "users/a%2Fb"
+		  users/{id} matched 6 of "users/a%2Fb" [from=/users/{id}], rejected by {id}: "a%2Fb" has an encoded slash
+		  search matched 0 of "users/a%2Fb" [from=/search?q={query}], rejected by search: expected "search" but got "users/"
+		no match for "/users/a%2Fb"
+	`)
+	explainEqual(t, tree, "/search?page=2", `
+		/ matched 1 of "/search": no children matched "search"
+		  users/{id} matched 0 of "search" [from=/users/{id}], rejected by users: expected "users" but got "searc"
+		  search matched 6 of "search" [from=/search?q={query}]: query doesn't match
+		no match for "/search"
+	`)
+	explainEqual(t, tree, "/search?q=go", `
+		/ matched 1 of "/search"
+		  users/{id} matched 0 of "search" [from=/users/{id}], rejected by users: expected "users" but got "searc"
+		  search matched 6 of "search" [from=/search?q={query}]
+		"/search" matched /search?q={query}
+	`)
+	actual := strings.TrimSpace(tree.ExplainMethod("post", "/users/10").String())
+	diff.TestString(t, actual, strings.ReplaceAll(strings.TrimSpace(`
+		/ matched 1 of "/users/10": no children matched "users/10"
+		  users/{id} matched 8 of "users/10" [from=/users/{id}]: method POST isn't allowed, allowed GET
+		  search matched 0 of "users/10" [from=/search?q={query}], rejected by search: expected "search" but got "users/"
+		no match for "/users/10"
+	`), "\t", ""))
+}
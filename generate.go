@@ -0,0 +1,716 @@
+package enroute
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/matthewmueller/enroute/ast"
+)
+
+// Generate compiles the tree into Go source for a package with the given name.
+// The generated matcher walks the same nodes as the tree in the same order,
+// using switch statements on literal prefixes, inline delimiter scans and
+// precompiled regexps, so it returns the same matches as Tree.Match. The file
+// declares:
+//
+//	func Match(input string) (*enroute.Match[string], error)
+//	func Lookup(input string, result *Result) bool
+//
+// Lookup doesn't allocate for paths that are already normalized. Trees with
+// hosts, queries, methods or the CaseRedirect option aren't supported. Route
+// metadata isn't generated. The generated file should go in its own package.
+func Generate(pkg string, tree *Tree[string]) ([]byte, error) {
+	if tree.hosts != nil && tree.hosts.root != nil {
+		return nil, fmt.Errorf("enroute: can't generate a matcher for routes with a host")
+	}
+	if tree.config.casing == CaseRedirect {
+		return nil, fmt.Errorf("enroute: can't generate a matcher for trees that redirect casing")
+	}
+	if tree.root == nil {
+		return nil, fmt.Errorf("enroute: can't generate a matcher for an empty tree")
+	}
+	g := &generator{ids: map[*Node[string]]int{}, patterns: map[string]int{}}
+	var err error
+	tree.each(tree.root, func(n *Node[string]) bool {
+		if err = checkGenerate(n); err != nil {
+			return false
+		}
+		g.ids[n] = len(g.nodes)
+		g.nodes = append(g.nodes, n)
+		if n.Label != "" {
+			g.routes = append(g.routes, n)
+			g.maxSlots = max(g.maxSlots, len(slotKeys(n.route)))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	g.header(pkg)
+	g.lookup()
+	for _, n := range g.nodes {
+		g.node(n)
+	}
+	g.helpers(tree.config.encodedSlash)
+	g.regexps()
+	source, err := format.Source(g.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("enroute: unable to format the generated matcher: %w", err)
+	}
+	return source, nil
+}
+
+// checkGenerate returns an error if the node's route can't be generated
+func checkGenerate(n *Node[string]) error {
+	switch {
+	case n.methods != nil:
+		return fmt.Errorf("enroute: can't generate a matcher for %q with methods", n.Label)
+	case len(n.variants) > 0 || (n.route != nil && len(n.route.Query) > 0):
+		return fmt.Errorf("enroute: can't generate a matcher for %q with a query", n.Label)
+	}
+	return nil
+}
+
+// generator writes the source of a generated matcher
+type generator struct {
+	bytes.Buffer
+	nodes    []*Node[string]       // Nodes in depth-first order
+	ids      map[*Node[string]]int // Index of each node in nodes
+	routes   []*Node[string]       // Nodes with a route
+	maxSlots int
+	patterns map[string]int // Index of each regexp by its pattern
+	order    []string       // Regexp patterns in the order they're used
+}
+
+func (g *generator) p(format string, args ...any) {
+	fmt.Fprintf(g, format, args...)
+	g.WriteByte('\n')
+}
+
+func (g *generator) header(pkg string) {
+	g.p("// Code generated by enroute. DO NOT EDIT.")
+	g.p("")
+	g.p("package %s", pkg)
+	g.p("")
+	g.p("import (")
+	g.p(`"fmt"`)
+	if g.hasRegexp() {
+		g.p(`"regexp"`)
+	}
+	g.p(`"strconv"`)
+	g.p(`"strings"`)
+	g.p(`"unicode/utf8"`)
+	g.p("")
+	g.p(`"github.com/matthewmueller/enroute"`)
+	g.p(`"github.com/matthewmueller/enroute/ast"`)
+	g.p(`"golang.org/x/text/unicode/norm"`)
+	g.p(")")
+	g.p("")
+}
+
+// hasRegexp is true if any node has a regexp slot
+func (g *generator) hasRegexp() bool {
+	for _, n := range g.nodes {
+		for _, section := range n.sections {
+			if _, ok := section.(*ast.RegexpSlot); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (g *generator) lookup() {
+	g.p("// Result of Lookup")
+	g.p("type Result struct {")
+	g.p("Route string // Route that matched")
+	g.p("Path  string // Normalized path that was matched")
+	g.p("Value string")
+	g.p("index int")
+	g.p("slots [%d]string", g.maxSlots)
+	g.p("starts [%d]int // Offset of each slot in Path", g.maxSlots)
+	g.p("n     int")
+	g.p("}")
+	g.p("")
+	g.p("// Slots returns the values of the route's slots in the normalized path in")
+	g.p("// order. Encoded characters are still encoded.")
+	g.p("func (r *Result) Slots() []string {")
+	g.p("return r.slots[:r.n]")
+	g.p("}")
+	g.p("")
+	g.p("type route struct {")
+	g.p("label string")
+	g.p("value string")
+	g.p("keys  []string")
+	g.p("types []string // Type of each slot, empty for slots without a type")
+	g.p("}")
+	g.p("")
+	g.p("var routes = [...]route{")
+	for _, n := range g.routes {
+		var keys, types []string
+		for _, section := range n.route.Sections {
+			slot, ok := section.(ast.Slot)
+			if !ok {
+				continue
+			}
+			keys = append(keys, strconv.Quote(slot.Slot()))
+			if typed, ok := slot.(*ast.TypedSlot); ok {
+				types = append(types, strconv.Quote(typed.TypeName))
+			} else {
+				types = append(types, `""`)
+			}
+		}
+		if len(keys) == 0 {
+			g.p("{%q, %q, nil, nil},", n.Label, n.Value)
+			continue
+		}
+		g.p("{%q, %q, []string{%s}, []string{%s}},", n.Label, n.Value, strings.Join(keys, ", "), strings.Join(types, ", "))
+	}
+	g.p("}")
+	g.p("")
+	g.p("// Match the input to a route like Tree.Match")
+	g.p("func Match(input string) (*enroute.Match[string], error) {")
+	g.p("var result Result")
+	g.p("if !Lookup(input, &result) {")
+	g.p(`return nil, fmt.Errorf("%%w for %%q", enroute.ErrNoMatch, result.Path)`)
+	g.p("}")
+	g.p("route := &routes[result.index]")
+	g.p("match := &enroute.Match[string]{Route: route.label, Path: result.Path, Value: route.value}")
+	g.p("// Raw values are cut from the path before it was normalized")
+	g.p("path, _ := cutQuery(input)")
+	g.p("original := trimTrailingSlash(path)")
+	g.p("var offsets []int")
+	g.p("if original != result.Path {")
+	g.p("_, offsets = normalizeOffsets(original)")
+	g.p("}")
+	g.p("for i, key := range route.keys {")
+	g.p("normalized := result.slots[i]")
+	g.p("raw := normalized")
+	g.p("if offsets != nil {")
+	g.p("start := result.starts[i]")
+	g.p("raw = original[offsets[start]:offsets[start+len(normalized)]]")
+	g.p("}")
+	g.p("slot := &enroute.Slot{Key: key, Value: decodeSlot(normalized), Raw: raw}")
+	g.p(`if route.types[i] != "" {`)
+	g.p("if t, ok := ast.LookupType(route.types[i]); ok {")
+	g.p("value, _ := ast.Unescape(normalized)")
+	g.p("slot.Parsed, _ = t.Parse(value)")
+	g.p("}")
+	g.p("}")
+	g.p("match.Slots = append(match.Slots, slot)")
+	g.p("}")
+	g.p("return match, nil")
+	g.p("}")
+	g.p("")
+	g.p("// Lookup matches the input to a route without allocating the slots. The")
+	g.p("// result is filled in even when nothing matches.")
+	g.p("func Lookup(input string, result *Result) bool {")
+	g.p("input, _ = cutQuery(input)")
+	g.p("input = normalize(trimTrailingSlash(input))")
+	g.p("result.Path = input")
+	g.p("result.n = 0")
+	g.p("_, path := splitHost(input)")
+	g.p("if len(path) == 0 || path[0] != '/' {")
+	g.p("return false")
+	g.p("}")
+	g.p("return match0(path, result)")
+	g.p("}")
+	g.p("")
+}
+
+// node writes the function that matches the node and its children
+func (g *generator) node(n *Node[string]) {
+	id := g.ids[n]
+	g.p("// match%d matches %s", id, n.sections.String())
+	g.p("func match%d(path string, r *Result) bool {", id)
+	g.p("n := r.n")
+	for _, section := range n.sections {
+		g.p("if len(path) == 0 {")
+		g.p("goto fail")
+		g.p("}")
+		g.section(section)
+	}
+	g.p("if len(path) == 0 {")
+	if n.Label == "" {
+		g.p("goto fail")
+	} else {
+		index := 0
+		for i, route := range g.routes {
+			if route == n {
+				index = i
+			}
+		}
+		g.p("r.Route, r.Value, r.index = %q, %q, %d", n.Label, n.Value, index)
+		g.p("return true")
+	}
+	g.p("}")
+	g.children(n.children)
+	g.p("fail:")
+	g.p("r.n = n")
+	g.p("return false")
+	g.p("}")
+	g.p("")
+}
+
+// section writes the code that matches the section and moves the path forward
+func (g *generator) section(section ast.Section) {
+	g.p("{")
+	defer g.p("}")
+	switch s := section.(type) {
+	case *ast.Slash:
+		g.p("if path[0] != '/' {")
+		g.p("goto fail")
+		g.p("}")
+		g.p("path = path[1:]")
+	case *ast.Path:
+		compare := "strings.EqualFold(%s, %q)"
+		if s.CaseSensitive {
+			compare = "%s == %q"
+		}
+		size := len(s.Value)
+		// Literals without a percent sign can be compared before decoding
+		if !strings.Contains(s.Value, "%") {
+			g.p("if len(path) >= %d && "+compare+" {", size, fmt.Sprintf("path[:%d]", size), s.Value)
+			g.p("path = path[%d:]", size)
+			g.p("} else if prefix, width, ok := decodePrefix(path, %d); ok && "+compare+" {", size, "prefix", s.Value)
+		} else {
+			g.p("if prefix, width, ok := decodePrefix(path, %d); ok && "+compare+" {", size, "prefix", s.Value)
+		}
+		g.p("path = path[width:]")
+		g.p("} else {")
+		g.p("goto fail")
+		g.p("}")
+	case *ast.WildcardSlot:
+		g.p("if !accept(path) {")
+		g.p("goto fail")
+		g.p("}")
+		g.p("r.slots[r.n] = path")
+		g.p("r.starts[r.n] = len(r.Path) - len(path)")
+		g.p("r.n++")
+		g.p(`path = ""`)
+	case *ast.OptionalSlot:
+		// Optional slots are expanded before they're inserted, so they never match
+		g.p("goto fail")
+	case ast.Slot:
+		g.delimiter(s)
+		g.p("if i == 0 {")
+		g.p("goto fail")
+		g.p("}")
+		g.p("value := path[:i]")
+		g.p("if !accept(value) {")
+		g.p("goto fail")
+		g.p("}")
+		switch s := s.(type) {
+		case *ast.RegexpSlot:
+			g.p("if decoded, _ := ast.Unescape(value); !pattern%d.MatchString(decoded) {", g.pattern(s.Pattern.String()))
+			g.p("goto fail")
+			g.p("}")
+		case *ast.TypedSlot:
+			g.p("if !parses(%q, value) {", s.TypeName)
+			g.p("goto fail")
+			g.p("}")
+		}
+		g.p("r.slots[r.n] = value")
+		g.p("r.starts[r.n] = len(r.Path) - len(path)")
+		g.p("r.n++")
+		g.p("path = path[i:]")
+	default:
+		panic(fmt.Sprintf("enroute: unable to generate a matcher for %T", section))
+	}
+}
+
+// delimiter writes the scan for the slot's first delimiter into i
+func (g *generator) delimiter(slot ast.Slot) {
+	var delimiters map[rune]bool
+	switch s := slot.(type) {
+	case *ast.RequiredSlot:
+		delimiters = s.Delimiters
+	case *ast.RegexpSlot:
+		delimiters = s.Delimiters
+	case *ast.TypedSlot:
+		delimiters = s.Delimiters
+	}
+	runes := make([]rune, 0, len(delimiters))
+	for r, ok := range delimiters {
+		if ok {
+			runes = append(runes, r)
+		}
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	switch {
+	case len(runes) == 0:
+		g.p("i := len(path)")
+		return
+	case len(runes) == 1 && runes[0] < utf8.RuneSelf:
+		g.p("i := strings.IndexByte(path, %s)", strconv.QuoteRune(runes[0]))
+	default:
+		g.p("i := strings.IndexAny(path, %q)", string(runes))
+	}
+	g.p("if i < 0 {")
+	g.p("i = len(path)")
+	g.p("}")
+}
+
+// children writes the code that tries each child in order. Only one literal
+// child can match, so they're dispatched on the path's first byte.
+func (g *generator) children(children nodes[string]) {
+	cases := map[byte][]int{}
+	var rest []int
+	for _, child := range children {
+		id := g.ids[child]
+		starts, ok := startBytes(child.sections[0])
+		if !ok {
+			rest = append(rest, id)
+			continue
+		}
+		for _, b := range starts {
+			cases[b] = append(cases[b], id)
+		}
+	}
+	if len(cases) > 0 {
+		keys := make([]int, 0, len(cases))
+		for b := range cases {
+			keys = append(keys, int(b))
+		}
+		sort.Ints(keys)
+		// Bytes that try the same children share a case
+		var order []string
+		labels := map[string][]string{}
+		for _, b := range keys {
+			call := calls(cases[byte(b)])
+			if _, ok := labels[call]; !ok {
+				order = append(order, call)
+			}
+			labels[call] = append(labels[call], byteLiteral(byte(b)))
+		}
+		g.p("switch path[0] {")
+		for _, call := range order {
+			g.p("case %s:", strings.Join(labels[call], ", "))
+			g.p("if %s {", call)
+			g.p("return true")
+			g.p("}")
+		}
+		g.p("}")
+	}
+	if len(rest) > 0 {
+		g.p("if %s {", calls(rest))
+		g.p("return true")
+		g.p("}")
+	}
+}
+
+// startBytes returns the first bytes of the paths the section can match.
+// Percent-encoded literals can start with a percent sign. Returns false if
+// the section can start with any byte.
+func startBytes(section ast.Section) ([]byte, bool) {
+	switch s := section.(type) {
+	case *ast.Slash:
+		return []byte{'/'}, true
+	case *ast.Path:
+		r, _ := utf8.DecodeRuneInString(s.Value)
+		if r == utf8.RuneError {
+			// Invalid bytes in the path compare equal to the replacement character
+			return nil, false
+		}
+		starts := []byte{'%', s.Value[0]}
+		if !s.CaseSensitive {
+			for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+				first := utf8.AppendRune(nil, f)[0]
+				if !bytes.Contains(starts, []byte{first}) {
+					starts = append(starts, first)
+				}
+			}
+		}
+		if s.Value[0] == '%' {
+			starts = starts[1:]
+		}
+		return starts, true
+	}
+	return nil, false
+}
+
+// byteLiteral formats the byte as a Go literal
+func byteLiteral(b byte) string {
+	if b < utf8.RuneSelf && strconv.IsPrint(rune(b)) {
+		return strconv.QuoteRune(rune(b))
+	}
+	return fmt.Sprintf("0x%02x", b)
+}
+
+// calls joins calls to the nodes' match functions
+func calls(ids []int) string {
+	calls := make([]string, len(ids))
+	for i, id := range ids {
+		calls[i] = fmt.Sprintf("match%d(path, r)", id)
+	}
+	return strings.Join(calls, " || ")
+}
+
+// pattern returns the index of the precompiled regexp
+func (g *generator) pattern(pattern string) int {
+	if index, ok := g.patterns[pattern]; ok {
+		return index
+	}
+	g.patterns[pattern] = len(g.order)
+	g.order = append(g.order, pattern)
+	return g.patterns[pattern]
+}
+
+func (g *generator) regexps() {
+	if len(g.order) == 0 {
+		return
+	}
+	g.p("var (")
+	for i, pattern := range g.order {
+		g.p("pattern%d = regexp.MustCompile(%q)", i, pattern)
+	}
+	g.p(")")
+}
+
+// helpers writes the functions the generated matcher shares with the tree
+func (g *generator) helpers(policy EncodedSlash) {
+	g.WriteString(generatedHelpers)
+	g.p("")
+	g.p("// accept is true if the slot's raw value is properly encoded and allowed by")
+	g.p("// the encoded slash policy")
+	g.p("func accept(raw string) bool {")
+	g.p("if strings.IndexByte(raw, '%%') < 0 {")
+	g.p("return true")
+	g.p("}")
+	if policy == RejectEncodedSlash {
+		g.p("if indexEncodedSlash(raw) >= 0 {")
+		g.p("return false")
+		g.p("}")
+	}
+	g.p("for i := 0; i < len(raw); i++ {")
+	g.p("if raw[i] != '%%' {")
+	g.p("continue")
+	g.p("}")
+	g.p("if i+2 >= len(raw) || !isHex(raw[i+1]) || !isHex(raw[i+2]) {")
+	g.p("return false")
+	g.p("}")
+	g.p("i += 2")
+	g.p("}")
+	g.p("return true")
+	g.p("}")
+	g.p("")
+	g.p("// decodeSlot decodes the slot's raw value")
+	g.p("func decodeSlot(raw string) string {")
+	if policy == KeepEncodedSlash {
+		g.p("var parts []string")
+		g.p("for {")
+		g.p("i := indexEncodedSlash(raw)")
+		g.p("if i < 0 {")
+		g.p("break")
+		g.p("}")
+		g.p("part, _ := ast.Unescape(raw[:i])")
+		g.p("parts = append(parts, part)")
+		g.p("raw = raw[i+3:]")
+		g.p("}")
+		g.p("part, _ := ast.Unescape(raw)")
+		g.p(`return strings.Join(append(parts, part), "%%2F")`)
+	} else {
+		g.p("value, _ := ast.Unescape(raw)")
+		g.p("return value")
+	}
+	g.p("}")
+	g.p("")
+}
+
+// generatedHelpers are copies of the tree's helpers for the generated matcher.
+// TestGenerateHelpers checks that the copies match the tree's helpers.
+const generatedHelpers = `
+// parses is true if the slot's type parses its raw value
+func parses(name, raw string) bool {
+	t, ok := ast.LookupType(name)
+	if !ok {
+		return false
+	}
+	value, _ := ast.Unescape(raw)
+	_, err := t.Parse(value)
+	return err == nil
+}
+
+// decodePrefix decodes the start of the path until it's n bytes long. It
+// returns the decoded prefix and how much of the path it took.
+func decodePrefix(path string, n int) (prefix string, width int, ok bool) {
+	if len(path) < n {
+		return "", 0, false
+	}
+	// Fast path for prefixes without any percent-encoding
+	if strings.IndexByte(path[:n], '%') < 0 {
+		return path[:n], n, true
+	}
+	decoded := make([]byte, 0, n)
+	for len(decoded) < n {
+		if width >= len(path) {
+			return "", 0, false
+		}
+		if path[width] != '%' {
+			decoded = append(decoded, path[width])
+			width++
+			continue
+		}
+		if width+2 >= len(path) || !isHex(path[width+1]) || !isHex(path[width+2]) {
+			return "", 0, false
+		}
+		decoded = append(decoded, unhex(path[width+1])<<4|unhex(path[width+2]))
+		width += 3
+	}
+	return string(decoded), width, true
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+// indexEncodedSlash returns the index of the first encoded slash or -1
+func indexEncodedSlash(raw string) int {
+	for i := 0; i+2 < len(raw); i++ {
+		if raw[i] == '%' && raw[i+1] == '2' && (raw[i+2] == 'F' || raw[i+2] == 'f') {
+			return i
+		}
+	}
+	return -1
+}
+
+// cutQuery cuts the input around the first question mark that's not within a
+// slot like {id?}
+func cutQuery(input string) (path, query string) {
+	depth := 0
+	for i := 0; i < len(input); i++ {
+		switch input[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '?':
+			if depth == 0 {
+				return input[:i], input[i+1:]
+			}
+		}
+	}
+	return input, ""
+}
+
+// trimTrailingSlash strips any trailing slash (e.g. /users/ => /users),
+// keeping the query if there is one
+func trimTrailingSlash(input string) string {
+	path, query := cutQuery(input)
+	path = strings.TrimRight(path, "/")
+	if len(path) == 0 {
+		path = "/"
+	}
+	if len(query) > 0 {
+		return path + "?" + query
+	}
+	return path
+}
+
+// normalize the path to NFC, so it matches routes written in any unicode form.
+// Percent-encoded unicode characters are decoded to be normalized too, while
+// encoded ASCII characters like %2F stay encoded.
+func normalize(path string) string {
+	normalized, _ := normalizeOffsets(path)
+	return normalized
+}
+
+// normalizeOffsets normalizes the path like normalize. It also returns the
+// offset in the path of each byte in the normalized path, followed by the
+// length of the path, so parts of the normalized path can be mapped back to
+// the path. The offsets are nil if the path didn't change.
+func normalizeOffsets(path string) (string, []int) {
+	decoded := path
+	var offsets []int
+	if strings.IndexByte(path, '%') >= 0 {
+		b := make([]byte, 0, len(path))
+		offsets = make([]int, 0, len(path)+1)
+		for i := 0; i < len(path); i++ {
+			offsets = append(offsets, i)
+			if path[i] == '%' && i+2 < len(path) {
+				if c, err := strconv.ParseUint(path[i+1:i+3], 16, 8); err == nil && c >= utf8.RuneSelf {
+					b = append(b, byte(c))
+					i += 2
+					continue
+				}
+			}
+			b = append(b, path[i])
+		}
+		if len(b) == len(path) {
+			// Nothing was decoded
+			offsets = nil
+		} else {
+			decoded = string(b)
+		}
+	}
+	if isASCII(decoded) || norm.NFC.IsNormalString(decoded) {
+		if offsets == nil {
+			return decoded, nil
+		}
+		return decoded, append(offsets, len(path))
+	}
+	if offsets == nil {
+		offsets = make([]int, len(decoded))
+		for i := range offsets {
+			offsets[i] = i
+		}
+	}
+	// Normalize segment by segment to keep track of where each segment started
+	var iter norm.Iter
+	iter.InitString(norm.NFC, decoded)
+	b := make([]byte, 0, len(decoded))
+	normalized := make([]int, 0, len(decoded)+1)
+	for !iter.Done() {
+		start := iter.Pos()
+		segment := iter.Next()
+		for range segment {
+			normalized = append(normalized, offsets[start])
+		}
+		b = append(b, segment...)
+	}
+	return string(b), append(normalized, len(path))
+}
+
+// isASCII is true if the path is ASCII, which is always normalized
+func isASCII(path string) bool {
+	for i := 0; i < len(path); i++ {
+		if path[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// splitHost splits the input into its host and path. Inputs have a host when
+// there's a dot before the first slash (e.g. acme.example.com/dashboard).
+func splitHost(input string) (host, path string) {
+	if strings.HasPrefix(input, "/") {
+		return "", input
+	}
+	host, path = input, "/"
+	if i := strings.IndexByte(input, '/'); i >= 0 {
+		host, path = input[:i], input[i:]
+	}
+	if !strings.Contains(host, ".") {
+		return "", input
+	}
+	return host, path
+}
+`
@@ -0,0 +1,123 @@
+package enroute_test
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/matthewmueller/diff"
+	"github.com/matthewmueller/enroute"
+)
+
+// The generated matcher is checked against the tree in internal/gentest
+
+func TestGenerate(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/users/{id}", "users/show"))
+	is.NoErr(tree.Insert("/posts/{id|[0-9]+}", "posts/show"))
+	source, err := enroute.Generate("routes", tree)
+	is.NoErr(err)
+	code := string(source)
+	is.True(strings.HasPrefix(code, "// Code generated by enroute. DO NOT EDIT.\n\npackage routes\n"))
+	is.True(strings.Contains(code, "func Match(input string) (*enroute.Match[string], error) {"))
+	is.True(strings.Contains(code, "func Lookup(input string, result *Result) bool {"))
+	is.True(strings.Contains(code, "case 'P', 'p':"))
+	is.True(strings.Contains(code, "i := strings.IndexByte(path, '/')"))
+	is.True(strings.Contains(code, `pattern0 = regexp.MustCompile("^[0-9]+$")`))
+}
+
+func TestGenerateUnsupported(t *testing.T) {
+	is := is.New(t)
+	_, err := enroute.Generate("routes", enroute.New())
+	is.Equal(err.Error(), "enroute: can't generate a matcher for an empty tree")
+	tree := enroute.New()
+	is.NoErr(tree.Insert("{tenant}.example.com/", "tenant"))
+	_, err = enroute.Generate("routes", tree)
+	is.Equal(err.Error(), "enroute: can't generate a matcher for routes with a host")
+	tree = enroute.New()
+	is.NoErr(tree.InsertMethod("GET", "/users", "users"))
+	_, err = enroute.Generate("routes", tree)
+	is.Equal(err.Error(), `enroute: can't generate a matcher for "/users" with methods`)
+	tree = enroute.New()
+	is.NoErr(tree.Insert("/search?q={query}", "search"))
+	_, err = enroute.Generate("routes", tree)
+	is.Equal(err.Error(), `enroute: can't generate a matcher for "/search?q={query}" with a query`)
+	tree = enroute.New(enroute.WithCase(enroute.CaseRedirect))
+	is.NoErr(tree.Insert("/users", "users"))
+	_, err = enroute.Generate("routes", tree)
+	is.Equal(err.Error(), "enroute: can't generate a matcher for trees that redirect casing")
+}
+
+// funcs returns the source of the functions in the file by name, without
+// their comments
+func funcs(t *testing.T, name string, source []byte) map[string]string {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, name, source, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	funcs := map[string]string{}
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil {
+			continue
+		}
+		out := new(bytes.Buffer)
+		if err := printer.Fprint(out, token.NewFileSet(), fn); err != nil {
+			t.Fatal(err)
+		}
+		funcs[fn.Name.Name] = out.String()
+	}
+	return funcs
+}
+
+// The generated matcher copies the tree's helpers, so they need to stay the
+// same as the tree's
+func TestGenerateHelpers(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/users/{id}", "users/show"))
+	source, err := enroute.Generate("routes", tree)
+	is.NoErr(err)
+	generated := funcs(t, "routes.go", source)
+	library := map[string]string{}
+	for _, pattern := range []string{"*.go", "ast/*.go"} {
+		files, err := filepath.Glob(pattern)
+		is.NoErr(err)
+		for _, file := range files {
+			if strings.HasSuffix(file, "_test.go") {
+				continue
+			}
+			source, err := os.ReadFile(file)
+			is.NoErr(err)
+			for name, fn := range funcs(t, file, source) {
+				library[name] = fn
+			}
+		}
+	}
+	helpers := []string{
+		"decodePrefix",
+		"isHex",
+		"unhex",
+		"indexEncodedSlash",
+		"cutQuery",
+		"trimTrailingSlash",
+		"normalize",
+		"normalizeOffsets",
+		"isASCII",
+		"splitHost",
+	}
+	for _, name := range helpers {
+		is.True(library[name] != "") // helper is in the library
+		diff.TestString(t, generated[name], library[name])
+	}
+}
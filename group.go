@@ -0,0 +1,171 @@
+package enroute
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Group of routes that share a prefix and metadata. Groups can be nested,
+// inheriting the prefix and metadata of their parent.
+type Group[V any] struct {
+	tree       *Tree[V]
+	parent     *Group[V]
+	prefix     string
+	tags       []string
+	middleware []string
+	auth       []string
+	err        error // Error returned when inserting into the group
+}
+
+// Group returns a group that inserts routes under the prefix (e.g. /admin)
+func (t *Tree[V]) Group(prefix string) *Group[V] {
+	return &Group[V]{tree: t, prefix: prefix}
+}
+
+// Group returns a nested group that inserts routes under the group's prefix
+// followed by the prefix. The prefix must start with a slash, otherwise
+// inserting into the group returns an error.
+func (g *Group[V]) Group(prefix string) *Group[V] {
+	group := &Group[V]{tree: g.tree, parent: g, prefix: joinPrefix(g.prefix, prefix), err: g.err}
+	if group.err == nil && prefix != "" && !strings.HasPrefix(prefix, "/") {
+		group.err = fmt.Errorf("enroute: prefix %q must start with a slash", prefix)
+	}
+	return group
+}
+
+// Prefix of the routes in the group, including the prefix of its parents
+func (g *Group[V]) Prefix() string {
+	return g.prefix
+}
+
+// Parent group or nil for top-level groups
+func (g *Group[V]) Parent() *Group[V] {
+	return g.parent
+}
+
+// Tag the routes in the group. Routes copy the group's tags into their Meta
+// when they're inserted, so only routes inserted after tagging get the tags.
+func (g *Group[V]) Tag(tags ...string) *Group[V] {
+	g.tags = append(g.tags, tags...)
+	return g
+}
+
+// Use the middleware IDs for the routes in the group. Like tags, routes copy
+// the middleware into their Meta when they're inserted.
+func (g *Group[V]) Use(middleware ...string) *Group[V] {
+	g.middleware = append(g.middleware, middleware...)
+	return g
+}
+
+// RequireAuth adds auth requirements (e.g. "admin") to the routes in the
+// group. Like tags, routes copy the requirements into their Meta when they're
+// inserted.
+func (g *Group[V]) RequireAuth(requirements ...string) *Group[V] {
+	g.auth = append(g.auth, requirements...)
+	return g
+}
+
+// Tags of the group, including the tags of its parents
+func (g *Group[V]) Tags() []string {
+	return g.inherit(func(g *Group[V]) []string { return g.tags })
+}
+
+// Middleware IDs of the group in order, starting with its parents' middleware
+func (g *Group[V]) Middleware() []string {
+	return g.inherit(func(g *Group[V]) []string { return g.middleware })
+}
+
+// Auth requirements of the group, including the requirements of its parents
+func (g *Group[V]) Auth() []string {
+	return g.inherit(func(g *Group[V]) []string { return g.auth })
+}
+
+// inherit collects the values from the top-level group down, skipping
+// duplicates
+func (g *Group[V]) inherit(fn func(g *Group[V]) []string) (values []string) {
+	if g.parent != nil {
+		values = g.parent.inherit(fn)
+	}
+	for _, value := range fn(g) {
+		if !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Insert a route under the group's prefix
+func (g *Group[V]) Insert(route string, value V) error {
+	route, err := g.route(route)
+	if err != nil {
+		return err
+	}
+	return g.tree.insertRoute(route, g.template(&Node[V]{Value: value}))
+}
+
+// InsertMethod inserts a route under the group's prefix that only handles the
+// given method
+func (g *Group[V]) InsertMethod(method string, route string, value V) error {
+	if method == "" {
+		return fmt.Errorf("enroute: method can't be empty for %q", route)
+	}
+	route, err := g.route(route)
+	if err != nil {
+		return err
+	}
+	return g.tree.insertRoute(route, g.template(&Node[V]{
+		methods: map[string]V{strings.ToUpper(method): value},
+	}))
+}
+
+// InsertNamed inserts a route under the group's prefix with a name that can be
+// used to build URLs
+func (g *Group[V]) InsertNamed(name string, route string, value V) error {
+	route, err := g.route(route)
+	if err != nil {
+		return err
+	}
+	return g.tree.insertNamed(name, route, g.template(&Node[V]{Value: value}))
+}
+
+// template links the node to the group. Routes start out with a copy of the
+// group's current tags, middleware and auth requirements in their metadata.
+func (g *Group[V]) template(n *Node[V]) *Node[V] {
+	n.group = g
+	meta := &Meta{Tags: g.Tags(), Middleware: g.Middleware(), Auth: g.Auth()}
+	if len(meta.Tags) > 0 || len(meta.Middleware) > 0 || len(meta.Auth) > 0 {
+		n.Meta = meta
+	}
+	return n
+}
+
+// route joins the group's prefix and the route
+func (g *Group[V]) route(route string) (string, error) {
+	if g.err != nil {
+		return "", g.err
+	}
+	prefix, err := g.tree.parse(g.prefix)
+	if err != nil {
+		return "", err
+	}
+	r, err := g.tree.parse(route)
+	if err != nil {
+		return "", err
+	}
+	return joinRoute(prefix, r, route)
+}
+
+// joinPrefix joins a nested group's prefix onto its parent's prefix
+func joinPrefix(parent, prefix string) string {
+	if prefix == "" || prefix == "/" {
+		return parent
+	}
+	return strings.TrimRight(parent, "/") + prefix
+}
+
+// Group the node's route was inserted through or nil if it was inserted
+// directly into the tree
+func (n *Node[V]) Group() *Group[V] {
+	return n.group
+}
@@ -0,0 +1,129 @@
+package enroute_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/matthewmueller/enroute"
+)
+
+func TestGroup(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/", "index"))
+	admin := tree.Group("/admin").Tag("admin").Use("auth", "log").RequireAuth("admin")
+	is.NoErr(admin.Insert("/", "admin/index"))
+	is.NoErr(admin.InsertMethod("post", "/users/{id}", "admin/users/update"))
+	billing := admin.Group("/billing/").Tag("billing").Use("audit", "log")
+	is.NoErr(billing.InsertNamed("invoice", "/invoices/{id}", "admin/billing/invoice"))
+	is.Equal(billing.Prefix(), "/admin/billing/")
+	is.Equal(billing.Parent(), admin)
+	is.Equal(billing.Tags(), []string{"admin", "billing"})
+	is.Equal(billing.Middleware(), []string{"auth", "log", "audit"})
+	is.Equal(billing.Auth(), []string{"admin"})
+	is.Equal(admin.Tags(), []string{"admin"})
+	match, err := tree.Match("/admin")
+	is.NoErr(err)
+	is.Equal(match.Value, "admin/index")
+	match, err = tree.MatchMethod("POST", "/admin/users/10")
+	is.NoErr(err)
+	is.Equal(match.String(), "/admin/users/{id} id=10")
+	// Matches carry the group's tags, middleware and auth requirements
+	is.Equal(match.Meta.Tags, []string{"admin"})
+	is.Equal(match.Meta.Middleware, []string{"auth", "log"})
+	is.Equal(match.Meta.Auth, []string{"admin"})
+	match, err = tree.Match("/admin/billing/invoices/10")
+	is.NoErr(err)
+	is.Equal(match.Meta.Tags, []string{"admin", "billing"})
+	is.Equal(match.Meta.Middleware, []string{"auth", "log", "audit"})
+	is.Equal(match.Meta.Auth, []string{"admin"})
+	url, err := tree.URL("invoice", &enroute.Slot{Key: "id", Value: "10"})
+	is.NoErr(err)
+	is.Equal(url, "/admin/billing/invoices/10")
+	// Nodes know which group they came from
+	groups := map[string]string{}
+	tree.Each(func(n *enroute.Node[string]) bool {
+		if n.Label == "" {
+			return true
+		}
+		if group := n.Group(); group != nil {
+			groups[n.Label] = group.Prefix()
+		} else {
+			groups[n.Label] = ""
+		}
+		return true
+	})
+	is.Equal(groups, map[string]string{
+		"/":                            "",
+		"/admin":                       "/admin",
+		"/admin/users/{id}":            "/admin",
+		"/admin/billing/invoices/{id}": "/admin/billing/",
+	})
+}
+
+func TestGroupSlots(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	org := tree.Group("{tenant}.example.com/orgs/{org}")
+	is.NoErr(org.Insert("/repos/{repo}", "repo"))
+	match, err := tree.Match("acme.example.com/orgs/enroute/repos/router")
+	is.NoErr(err)
+	is.Equal(match.String(), "{tenant}.example.com/orgs/{org}/repos/{repo} tenant=acme&org=enroute&repo=router")
+	err = org.Insert("/{org}", "org")
+	is.Equal(err.Error(), `enroute: slot "org" in "/{org}" is already in the prefix "{tenant}.example.com/orgs/{org}"`)
+	err = org.InsertMethod("", "/", "org")
+	is.Equal(err.Error(), `enroute: method can't be empty for "/"`)
+}
+
+func TestGroupDuplicate(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/admin/users", "users"))
+	err := tree.Group("/admin").Insert("/users", "users")
+	is.True(errors.Is(err, enroute.ErrDuplicate))
+	is.Equal(err.Error(), `route already exists "/admin/users"`)
+}
+
+func TestGroupPrefixSlash(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	users := tree.Group("/api").Group("users")
+	err := users.Insert("/{id}", "user")
+	is.Equal(err.Error(), `enroute: prefix "users" must start with a slash`)
+	err = users.Group("/{id}").Insert("/edit", "edit")
+	is.Equal(err.Error(), `enroute: prefix "users" must start with a slash`)
+	_, err = tree.Match("/apiusers/10")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+}
+
+func TestGroupTagAfterInsert(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	admin := tree.Group("/admin").Tag("admin")
+	is.NoErr(admin.Insert("/users", "users"))
+	admin.Tag("late")
+	is.NoErr(admin.Insert("/posts", "posts"))
+	// Routes keep the tags the group had when they were inserted
+	match, err := tree.Match("/admin/users")
+	is.NoErr(err)
+	is.Equal(match.Meta.Tags, []string{"admin"})
+	match, err = tree.Match("/admin/posts")
+	is.NoErr(err)
+	is.Equal(match.Meta.Tags, []string{"admin", "late"})
+	node, err := tree.Find("/admin/users")
+	is.NoErr(err)
+	is.Equal(node.Group().Tags(), []string{"admin", "late"})
+}
+
+func TestMountGroup(t *testing.T) {
+	is := is.New(t)
+	sub := enroute.New()
+	api := sub.Group("/api").Tag("api")
+	is.NoErr(api.Insert("/users", "users"))
+	tree := enroute.New()
+	is.NoErr(tree.Mount("/v1", sub))
+	node, err := tree.Find("/v1/api/users")
+	is.NoErr(err)
+	is.Equal(node.Group(), api)
+}
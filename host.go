@@ -0,0 +1,120 @@
+package enroute
+
+import (
+	"strings"
+
+	"github.com/matthewmueller/enroute/ast"
+)
+
+// Routes with a host like {tenant}.example.com/dashboard are stored in a path
+// tree for each host pattern. The host patterns have their own tree in front
+// of the path trees, so hosts follow the same precedence as paths. Host
+// patterns are stored with a leading slash, so they share the same root.
+
+// insertHost inserts a route with a host into the host's path tree
+func (t *Tree[V]) insertHost(r *ast.Route, template *Node[V]) error {
+	if tree := t.findHost(r.Host); tree != nil {
+		return tree.insertParsed(r, template)
+	}
+	tree := &Tree[V]{config: t.config}
+	if err := tree.insertParsed(r, template); err != nil {
+		return err
+	}
+	if t.hosts == nil {
+		t.hosts = new(Tree[string])
+	}
+	label := r.Host.String()
+	sections := hostSections(r.Host)
+	err := t.hosts.insert(&Node[string]{
+		Label:    label,
+		route:    &ast.Route{Sections: sections},
+		sections: sections,
+	})
+	if err != nil {
+		return err
+	}
+	if t.trees == nil {
+		t.trees = map[string]*Tree[V]{}
+	}
+	t.trees[label] = tree
+	return nil
+}
+
+// findHost finds the path tree for the host pattern or returns nil
+func (t *Tree[V]) findHost(host ast.Sections) *Tree[V] {
+	if t.hosts == nil || t.hosts.root == nil {
+		return nil
+	}
+	label := host.String()
+	node, err := t.hosts.root.find(label, hostSections(host))
+	if err != nil || node.Label != label {
+		return nil
+	}
+	return t.trees[label]
+}
+
+// deleteHost deletes the route with the label from the host's path tree. The
+// host pattern is removed once it doesn't have any routes left.
+func (t *Tree[V]) deleteHost(host ast.Sections, label string) bool {
+	tree := t.findHost(host)
+	if tree == nil || !tree.delete(label) {
+		return false
+	}
+	if tree.root == nil {
+		t.hosts.delete(host.String())
+		delete(t.trees, host.String())
+	}
+	return true
+}
+
+// hostMatch is a host pattern that matches the input's host
+type hostMatch struct {
+	node       *Node[string]
+	slotValues []slotValue
+}
+
+// matchHost returns every host pattern that matches the host in match order
+func (t *Tree[V]) matchHost(host string) (matches []hostMatch) {
+	if t.hosts == nil || t.hosts.root == nil {
+		return nil
+	}
+	t.hosts.root.matches(new(matcher), "/"+host, []slotValue{}, func(node *Node[string], slotValues []slotValue) bool {
+		matches = append(matches, hostMatch{node, slotValues})
+		return true
+	})
+	return matches
+}
+
+// eachHost calls fn for each host pattern in match order with its path tree
+func (t *Tree[V]) eachHost(fn func(host string, tree *Tree[V])) {
+	if t.hosts == nil {
+		return
+	}
+	t.hosts.Each(func(n *Node[string]) bool {
+		if n.Label != "" {
+			fn(n.Label, t.trees[n.Label])
+		}
+		return true
+	})
+}
+
+// hostSections returns the host's sections with a leading slash
+func hostSections(host ast.Sections) ast.Sections {
+	return append(ast.Sections{&ast.Slash{Value: "/"}}, host...)
+}
+
+// splitHost splits the input into its host and path. Inputs have a host when
+// there's a dot before the first slash (e.g. acme.example.com/dashboard).
+func splitHost(input string) (host, path string) {
+	if strings.HasPrefix(input, "/") {
+		return "", input
+	}
+	host, path = input, "/"
+	if i := strings.IndexByte(input, '/'); i >= 0 {
+		host, path = input[:i], input[i:]
+	}
+	if !strings.Contains(host, ".") {
+		return "", input
+	}
+	return host, path
+}
@@ -0,0 +1,155 @@
+package enroute_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/matthewmueller/diff"
+	"github.com/matthewmueller/enroute"
+)
+
+func TestInsertHost(t *testing.T) {
+	tree := enroute.New()
+	tree.MustInsert("/dashboard", "dashboard")
+	tree.MustInsert("{tenant}.example.com/dashboard", "tenant/dashboard")
+	tree.MustInsert("{tenant}.example.com/users/{id}", "tenant/users")
+	tree.MustInsert("api.example.com/users/{id}", "api/users")
+	tree.MustInsert("{tenant|[a-z]+}.example.org", "tenant/index")
+	diff.TestString(t, `
+/dashboard [from=/dashboard]
+api.example.com
+•••••••••••••••/users/{id} [from=api.example.com/users/{id}]
+{tenant|^[a-z]+$}.example.org
+•••••••••••••••••••••••••••••/ [from={tenant|^[a-z]+$}.example.org/]
+{tenant}.example.com
+••••••••••••••••••••/
+•••••••••••••••••••••dashboard [from={tenant}.example.com/dashboard]
+•••••••••••••••••••••users/{id} [from={tenant}.example.com/users/{id}]
+`[1:], tree.String())
+}
+
+func TestMatchHost(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/dashboard", "dashboard"))
+	is.NoErr(tree.Insert("{tenant}.example.com/dashboard", "tenant/dashboard"))
+	is.NoErr(tree.Insert("{tenant}.example.com/users/{id}", "tenant/users"))
+	is.NoErr(tree.Insert("api.example.com/users/{id}", "api/users"))
+	is.NoErr(tree.Insert("{id:int}.example.com/users/{id}", "numbered"))
+	is.NoErr(tree.Insert("{region}-{tenant}.example.net", "region"))
+	match, err := tree.Match("acme.example.com/dashboard")
+	is.NoErr(err)
+	is.Equal(match.Route, "{tenant}.example.com/dashboard")
+	is.Equal(match.Value, "tenant/dashboard")
+	is.Equal(match.Path, "acme.example.com/dashboard")
+	is.Equal(match.String(), "{tenant}.example.com/dashboard tenant=acme")
+	// Host slots come before path slots
+	match, err = tree.Match("acme.example.com/users/10")
+	is.NoErr(err)
+	is.Equal(match.String(), "{tenant}.example.com/users/{id} tenant=acme&id=10")
+	// Exact hosts take precedence over slots
+	match, err = tree.Match("API.example.com/users/10")
+	is.NoErr(err)
+	is.Equal(match.String(), "api.example.com/users/{id} id=10")
+	// Typed hosts take precedence over required slots
+	match, err = tree.Match("42.example.com/users/10")
+	is.NoErr(err)
+	is.Equal(match.Value, "numbered")
+	// Host slots are delimited by dots
+	match, err = tree.Match("a.b.example.com/dashboard")
+	is.NoErr(err)
+	is.Equal(match.Route, "/dashboard")
+	match, err = tree.Match("eu-acme.example.net")
+	is.NoErr(err)
+	is.Equal(match.String(), "{region}-{tenant}.example.net/ region=eu&tenant=acme")
+	// Fallback to routes without a host
+	match, err = tree.Match("acme.example.org/dashboard")
+	is.NoErr(err)
+	is.Equal(match.Route, "/dashboard")
+	// Hosts that match with slots are tried after exact hosts
+	match, err = tree.Match("api.example.com/dashboard")
+	is.NoErr(err)
+	is.Equal(match.String(), "{tenant}.example.com/dashboard tenant=api")
+	_, err = tree.Match("acme.example.com/settings")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	// Paths without a host only match routes without a host
+	_, err = tree.Match("/users/10")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+}
+
+func TestMatchHostFallthrough(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("api.example.com/users", "api/users"))
+	is.NoErr(tree.Insert("{tenant}.example.com/dashboard", "tenant/dashboard"))
+	is.NoErr(tree.Insert("{region}.{domain}.com/status", "status"))
+	match, err := tree.Match("api.example.com/dashboard")
+	is.NoErr(err)
+	is.Equal(match.String(), "{tenant}.example.com/dashboard tenant=api")
+	match, err = tree.Match("api.example.com/users")
+	is.NoErr(err)
+	is.Equal(match.Value, "api/users")
+	// Every matching host is tried in order
+	match, err = tree.Match("api.example.com/status")
+	is.NoErr(err)
+	is.Equal(match.String(), "{region}.{domain}.com/status region=api&domain=example")
+	explanation := tree.Explain("api.example.com/dashboard")
+	is.Equal(explanation.Route, "{tenant}.example.com/dashboard")
+}
+
+func TestHostErrors(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("{tenant}.example.com/dashboard", "dashboard"))
+	err := tree.Insert("{tenant}.example.com/dashboard", "dashboard")
+	is.True(errors.Is(err, enroute.ErrDuplicate))
+	err = tree.Insert("{account}.example.com/settings", "settings")
+	is.True(errors.Is(err, enroute.ErrDuplicate))
+	is.Equal(tree.Insert("{tenant?}.example.com/", "index").Error(), "optional slots aren't supported in hosts")
+	is.Equal(tree.Insert("{tenant*}.example.com/", "index").Error(), "wildcard slots aren't supported in hosts")
+	is.Equal(tree.Insert("Example.com/", "index").Error(), "unexpected character 'E' in host")
+	// The failed inserts didn't leave anything behind
+	_, err = tree.Match("acme.example.com/settings")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+}
+
+func TestDeleteHost(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("{tenant}.example.com/dashboard", "dashboard"))
+	is.NoErr(tree.Insert("{tenant}.example.com/settings", "settings"))
+	is.NoErr(tree.Insert("/dashboard", "root"))
+	is.NoErr(tree.Delete("{tenant}.example.com/dashboard"))
+	match, err := tree.Match("acme.example.com/dashboard")
+	is.NoErr(err)
+	is.Equal(match.Value, "root")
+	is.NoErr(tree.Delete("{tenant}.example.com/settings"))
+	is.Equal(tree.String(), "/dashboard [from=/dashboard]\n")
+	err = tree.Delete("{tenant}.example.com/settings")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+}
+
+func TestHostClone(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("{tenant}.example.com/dashboard", "dashboard"))
+	clone := tree.Clone()
+	is.NoErr(clone.Insert("{tenant}.example.com/settings", "settings"))
+	_, err := tree.Match("acme.example.com/settings")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	_, err = clone.Match("acme.example.com/settings")
+	is.NoErr(err)
+}
+
+func TestHostURL(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.InsertNamed("dashboard", "{tenant}.example.com/dashboard", "dashboard"))
+	url, err := tree.URL("dashboard", &enroute.Slot{Key: "tenant", Value: "acme"})
+	is.NoErr(err)
+	is.Equal(url, "acme.example.com/dashboard")
+	node, err := tree.Find("{tenant}.example.com/dashboard")
+	is.NoErr(err)
+	is.Equal(node.Label, "{tenant}.example.com/dashboard")
+}
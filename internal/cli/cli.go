@@ -0,0 +1,277 @@
+// Package cli is the enroute command for listing, testing and linting route
+// files
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/matthewmueller/enroute"
+	"github.com/matthewmueller/enroute/routefile"
+)
+
+// Exit codes
+const (
+	ExitOK      = 0 // Everything is fine
+	ExitProblem = 1 // The routes have problems or the path didn't match
+	ExitUsage   = 2 // The command was used incorrectly or the file can't be read
+)
+
+const usage = `Usage: enroute [-f file] <command> [arguments]
+
+Commands:
+  ls [-flat]              print the route tree or a flat route table
+  match [-method M] path  show the route that matches the path and its slots
+  check [-strict]         check the routes for errors and ambiguities
+  fmt [-w]                rewrite the routes into their canonical form
+
+Flags:
+  -f file  route file to read (default "routes.txt")
+`
+
+// CLI runs the enroute command
+type CLI struct {
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// New CLI that writes to stdout and stderr
+func New() *CLI {
+	return &CLI{os.Stdout, os.Stderr}
+}
+
+// Run the command with the arguments and return the exit code
+func (c *CLI) Run(args ...string) int {
+	fset := flag.NewFlagSet("enroute", flag.ContinueOnError)
+	fset.SetOutput(io.Discard)
+	file := fset.String("f", "routes.txt", "route file to read")
+	if err := fset.Parse(args); err != nil || fset.NArg() == 0 {
+		fmt.Fprint(c.Stderr, usage)
+		return ExitUsage
+	}
+	command, args := fset.Arg(0), fset.Args()[1:]
+	switch command {
+	case "ls":
+		return c.ls(*file, args)
+	case "match":
+		return c.match(*file, args)
+	case "check":
+		return c.check(*file, args)
+	case "fmt":
+		return c.fmt(*file, args)
+	default:
+		fmt.Fprintf(c.Stderr, "enroute: unknown command %q\n\n%s", command, usage)
+		return ExitUsage
+	}
+}
+
+// flags parses the command's flags
+func (c *CLI) flags(name string, args []string, define func(fset *flag.FlagSet)) (*flag.FlagSet, bool) {
+	fset := flag.NewFlagSet(name, flag.ContinueOnError)
+	fset.SetOutput(io.Discard)
+	define(fset)
+	if err := fset.Parse(args); err != nil {
+		fmt.Fprintf(c.Stderr, "enroute %s: %s\n\n%s", name, err, usage)
+		return nil, false
+	}
+	return fset, true
+}
+
+// tree reads the route file into a tree. Returns the exit code if the file
+// can't be read or has errors.
+func (c *CLI) tree(file string) (*enroute.Tree[string], int) {
+	routes, code := c.read(file)
+	if routes == nil {
+		return nil, code
+	}
+	tree := enroute.New()
+	if err := routefile.Load(tree, routes); err != nil {
+		fmt.Fprintln(c.Stderr, err)
+		return nil, ExitProblem
+	}
+	return tree, ExitOK
+}
+
+// read the routes from the route file and its includes. Returns the exit code
+// if the file can't be read or has errors.
+func (c *CLI) read(file string) ([]*routefile.Route, int) {
+	routes, err := routefile.ReadFile(file)
+	if err != nil {
+		return nil, c.fail(err)
+	}
+	return routes, ExitOK
+}
+
+// fail reports the error and returns its exit code. Errors in the route file
+// are problems, while other errors mean the file couldn't be read.
+func (c *CLI) fail(err error) int {
+	var fileErr *routefile.Error
+	if errors.As(err, &fileErr) {
+		fmt.Fprintln(c.Stderr, err)
+		return ExitProblem
+	}
+	fmt.Fprintf(c.Stderr, "enroute: %s\n", err)
+	return ExitUsage
+}
+
+// ls prints the route tree or a flat table of the routes in match order
+func (c *CLI) ls(file string, args []string) int {
+	var flat bool
+	fset, ok := c.flags("ls", args, func(fset *flag.FlagSet) {
+		fset.BoolVar(&flat, "flat", false, "print a flat route table")
+	})
+	if !ok || fset.NArg() > 0 {
+		return ExitUsage
+	}
+	tree, code := c.tree(file)
+	if tree == nil {
+		return code
+	}
+	if !flat {
+		fmt.Fprint(c.Stdout, tree.String())
+		return ExitOK
+	}
+	w := tabwriter.NewWriter(c.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "METHOD\tROUTE\tVALUE")
+	for route := range tree.SortedRoutes() {
+		if route.Methods == nil {
+			fmt.Fprintf(w, "*\t%s\t%s\n", route.Route, route.Value)
+			continue
+		}
+		methods := make([]string, 0, len(route.Methods))
+		for method := range route.Methods {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", method, route.Route, route.Methods[method])
+		}
+	}
+	w.Flush()
+	return ExitOK
+}
+
+// match prints the route that matches the path and its slots
+func (c *CLI) match(file string, args []string) int {
+	var method string
+	fset, ok := c.flags("match", args, func(fset *flag.FlagSet) {
+		fset.StringVar(&method, "method", "", "method to match")
+	})
+	if !ok {
+		return ExitUsage
+	}
+	if fset.NArg() != 1 {
+		fmt.Fprintf(c.Stderr, "enroute match: expected a path\n\n%s", usage)
+		return ExitUsage
+	}
+	tree, code := c.tree(file)
+	if tree == nil {
+		return code
+	}
+	var match *enroute.Match[string]
+	var err error
+	if method == "" {
+		match, err = tree.Match(fset.Arg(0))
+	} else {
+		match, err = tree.MatchMethod(method, fset.Arg(0))
+	}
+	if err != nil {
+		fmt.Fprintf(c.Stderr, "enroute: %s\n", err)
+		return ExitProblem
+	}
+	w := tabwriter.NewWriter(c.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "route:\t%s\n", match.Route)
+	fmt.Fprintf(w, "value:\t%s\n", match.Value)
+	for _, slot := range match.Slots {
+		fmt.Fprintf(w, "%s:\t%s\n", slot.Key, slot.Value)
+	}
+	w.Flush()
+	return ExitOK
+}
+
+// check reports routes that don't parse, duplicate routes and routes that
+// overlap or are unreachable. With -strict, routes that win by precedence are
+// reported too.
+func (c *CLI) check(file string, args []string) int {
+	var strict bool
+	fset, ok := c.flags("check", args, func(fset *flag.FlagSet) {
+		fset.BoolVar(&strict, "strict", false, "also report routes that win by precedence")
+	})
+	if !ok || fset.NArg() > 0 {
+		return ExitUsage
+	}
+	routes, code := c.read(file)
+	if routes == nil {
+		return code
+	}
+	tree := enroute.New()
+	var errs []error
+	if err := routefile.Load(tree, routes); err != nil {
+		errs = append(errs, err)
+	}
+	for _, problem := range tree.Analyze() {
+		if problem.Kind == enroute.Precedence && !strict {
+			continue
+		}
+		if pos, ok := positionOf(tree, routes, problem.Route); ok {
+			errs = append(errs, fmt.Errorf("%s: %s", pos, problem))
+			continue
+		}
+		errs = append(errs, fmt.Errorf("enroute: %s", problem))
+	}
+	for _, err := range errs {
+		fmt.Fprintln(c.Stderr, err)
+	}
+	if len(errs) > 0 {
+		return ExitProblem
+	}
+	return ExitOK
+}
+
+// positionOf returns the position of the route in the route files. Ok is
+// false if none of the routes inserted the route.
+func positionOf(tree *enroute.Tree[string], routes []*routefile.Route, label string) (pos routefile.Position, ok bool) {
+	for _, route := range routes {
+		if node, err := tree.Find(route.Route); err == nil && node.Label == label {
+			return route.Pos, true
+		}
+	}
+	return pos, false
+}
+
+// fmt rewrites the routes into their canonical form and aligns the columns.
+// Included files aren't formatted.
+func (c *CLI) fmt(file string, args []string) int {
+	var write bool
+	fset, ok := c.flags("fmt", args, func(fset *flag.FlagSet) {
+		fset.BoolVar(&write, "w", false, "write the result to the file")
+	})
+	if !ok || fset.NArg() > 0 {
+		return ExitUsage
+	}
+	source, err := os.ReadFile(file)
+	if err != nil {
+		return c.fail(err)
+	}
+	parsed, err := routefile.Parse(file, string(source))
+	if err != nil {
+		return c.fail(err)
+	}
+	formatted, err := parsed.Format()
+	if err != nil {
+		return c.fail(err)
+	}
+	if !write {
+		fmt.Fprint(c.Stdout, formatted)
+		return ExitOK
+	}
+	if err := os.WriteFile(file, []byte(formatted), 0644); err != nil {
+		return c.fail(err)
+	}
+	return ExitOK
+}
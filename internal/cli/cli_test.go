@@ -0,0 +1,148 @@
+package cli_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/matthewmueller/diff"
+	"github.com/matthewmueller/enroute/internal/cli"
+)
+
+// run the command against a route file with the contents
+func run(t *testing.T, routes string, args ...string) (stdout, stderr string, code int, file string) {
+	t.Helper()
+	file = filepath.Join(t.TempDir(), "routes.txt")
+	if err := os.WriteFile(file, []byte(routes), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, errs := new(bytes.Buffer), new(bytes.Buffer)
+	code = (&cli.CLI{Stdout: out, Stderr: errs}).Run(append([]string{"-f", file}, args...)...)
+	return out.String(), errs.String(), code, file
+}
+
+const routes = `
+# Users
+GET /users/{id}  users.show # show a user
+POST /users users.create
+/posts/{id|[0-9]+}/  posts.show
+/posts/{slug} posts.slug
+`
+
+func TestLs(t *testing.T) {
+	is := is.New(t)
+	stdout, _, code, _ := run(t, routes, "ls")
+	is.Equal(code, cli.ExitOK)
+	diff.TestString(t, `
+/
+•users [from=/users, methods=POST]
+••••••/{id} [from=/users/{id}, methods=GET]
+•posts/
+•••••••{id|^[0-9]+$} [from=/posts/{id|^[0-9]+$}]
+•••••••{slug} [from=/posts/{slug}]
+`[1:], stdout)
+	stdout, _, code, _ = run(t, routes, "ls", "-flat")
+	is.Equal(code, cli.ExitOK)
+	diff.TestString(t, `
+METHOD  ROUTE                 VALUE
+*       /posts/{id|^[0-9]+$}  posts.show
+*       /posts/{slug}         posts.slug
+POST    /users                users.create
+GET     /users/{id}           users.show
+`[1:], stdout)
+}
+
+func TestMatch(t *testing.T) {
+	is := is.New(t)
+	stdout, _, code, _ := run(t, routes, "match", "/posts/10")
+	is.Equal(code, cli.ExitOK)
+	diff.TestString(t, `
+route:  /posts/{id|^[0-9]+$}
+value:  posts.show
+id:     10
+`[1:], stdout)
+	stdout, _, code, _ = run(t, routes, "match", "-method", "get", "/users/10")
+	is.Equal(code, cli.ExitOK)
+	is.Equal(stdout, "route:  /users/{id}\nvalue:  users.show\nid:     10\n")
+	_, stderr, code, _ := run(t, routes, "match", "-method", "DELETE", "/users/10")
+	is.Equal(code, cli.ExitProblem)
+	is.Equal(stderr, "enroute: method DELETE not allowed for \"/users/10\", allowed GET\n")
+	_, stderr, code, _ = run(t, routes, "match", "/comments")
+	is.Equal(code, cli.ExitProblem)
+	is.Equal(stderr, "enroute: no match for \"/comments\"\n")
+	_, _, code, _ = run(t, routes, "match")
+	is.Equal(code, cli.ExitUsage)
+}
+
+func TestCheck(t *testing.T) {
+	is := is.New(t)
+	_, stderr, code, _ := run(t, routes, "check")
+	is.Equal(stderr, "")
+	is.Equal(code, cli.ExitOK)
+	_, stderr, code, file := run(t, routes, "check", "-strict")
+	is.Equal(code, cli.ExitProblem)
+	is.Equal(stderr, file+`:5:1: "/posts/{id|^[0-9]+$}" and "/posts/{slug}" both match /posts/0, "/posts/{id|^[0-9]+$}" wins by precedence`+"\n")
+	_, stderr, code, file = run(t, routes+"/posts/{n|[0-5]+} posts.n\n/users/{name} users.name\n/{ posts.bad\n", "check")
+	is.Equal(code, cli.ExitProblem)
+	diff.TestString(t, ""+
+		file+`:8:1: route "/users/{name}" is ambiguous with "/users/{id}"`+"\n"+
+		file+`:9:3: unclosed slot`+"\n"+
+		file+`:7:1: "/posts/{n|^[0-5]+$}" is unreachable, "/posts/{id|^[0-9]+$}" matches first (e.g. /posts/0)`+"\n",
+		stderr)
+	_, stderr, code, file = run(t, "GET\n", "check")
+	is.Equal(code, cli.ExitProblem)
+	is.Equal(stderr, file+":1:4: missing route after GET\n")
+}
+
+func TestFmt(t *testing.T) {
+	is := is.New(t)
+	stdout, _, code, _ := run(t, routes+"/search?q={query}&page={page?} search\n", "fmt")
+	is.Equal(code, cli.ExitOK)
+	diff.TestString(t, `
+# Users
+GET   /users/{id}                     users.show    # show a user
+POST  /users                          users.create
+      /posts/{id|[0-9]+}              posts.show
+      /posts/{slug}                   posts.slug
+      /search?page={page?}&q={query}  search
+`, stdout)
+	_, _, code, file := run(t, "/users/  users\n/about about\n", "fmt", "-w")
+	is.Equal(code, cli.ExitOK)
+	formatted, err := os.ReadFile(file)
+	is.NoErr(err)
+	is.Equal(string(formatted), "/users  users\n/about  about\n")
+}
+
+func TestUsage(t *testing.T) {
+	is := is.New(t)
+	_, stderr, code, _ := run(t, routes)
+	is.Equal(code, cli.ExitUsage)
+	is.True(strings.HasPrefix(stderr, "Usage: enroute"))
+	_, stderr, code, _ = run(t, routes, "serve")
+	is.Equal(code, cli.ExitUsage)
+	is.True(strings.HasPrefix(stderr, `enroute: unknown command "serve"`))
+	out, errs := new(bytes.Buffer), new(bytes.Buffer)
+	code = (&cli.CLI{Stdout: out, Stderr: errs}).Run("-f", filepath.Join(t.TempDir(), "missing.txt"), "ls")
+	is.Equal(code, cli.ExitUsage)
+	is.Equal(out.String(), "")
+	is.True(bytes.Contains(errs.Bytes(), []byte("no such file or directory")))
+}
+
+func TestInclude(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	admin := filepath.Join(dir, "admin.txt")
+	is.NoErr(os.WriteFile(admin, []byte("/users/{id} admin.users\n/users/{name} admin.names\n"), 0644))
+	file := filepath.Join(dir, "routes.txt")
+	is.NoErr(os.WriteFile(file, []byte("/ home\ninclude admin.txt /admin\n"), 0644))
+	out, errs := new(bytes.Buffer), new(bytes.Buffer)
+	code := (&cli.CLI{Stdout: out, Stderr: errs}).Run("-f", file, "check")
+	is.Equal(code, cli.ExitProblem)
+	is.Equal(errs.String(), admin+`:2:1: route "/admin/users/{name}" is ambiguous with "/admin/users/{id}"`+"\n")
+	out, errs = new(bytes.Buffer), new(bytes.Buffer)
+	code = (&cli.CLI{Stdout: out, Stderr: errs}).Run("-f", file, "match", "/admin/users/10")
+	is.Equal(code, cli.ExitProblem)
+}
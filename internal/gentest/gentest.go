@@ -0,0 +1,39 @@
+// Package gentest checks the matcher generated from a tree against the tree
+// itself. Run `go test ./internal/gentest -update` to regenerate the matcher.
+package gentest
+
+import "github.com/matthewmueller/enroute"
+
+// Routes in the tree the matcher is generated from
+var Routes = []string{
+	"/",
+	"/about",
+	"/api/docs",
+	"/café/{name}",
+	"/日本/{id}",
+	"/users/{id}",
+	"/users/{id}/edit",
+	"/users/{id:int}",
+	"/users/{id}/{path*}",
+	"/posts/{id|[0-9]+}",
+	"/posts/{post_id}/comments/{id?}",
+	"/posts/{id:int}/{day:date}/{slug}",
+	"/tokens/{token:uuid}",
+	"/fly/{from}-{to}",
+	"/fly/{from}.{format}",
+	"/v{major|[0-9]+}",
+	"/v{major|[0-9]+}.{minor|[0-9]+}",
+	"/files/{path*}",
+	"/{owner}/{repo}/{branch}/{path*}",
+	"/{owner}/{repo}/tree",
+	"/{slug|[a-z]+}-{id:int}",
+}
+
+// Tree returns the tree the matcher is generated from
+func Tree() *enroute.Tree[string] {
+	tree := enroute.New()
+	for _, route := range Routes {
+		tree.MustInsert(route, route)
+	}
+	return tree
+}
@@ -0,0 +1,156 @@
+package gentest_test
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/matthewmueller/diff"
+	"github.com/matthewmueller/enroute"
+	"github.com/matthewmueller/enroute/internal/gentest"
+)
+
+var update = flag.Bool("update", false, "update the generated matcher")
+
+func TestGenerated(t *testing.T) {
+	is := is.New(t)
+	source, err := enroute.Generate("gentest", gentest.Tree())
+	is.NoErr(err)
+	if *update {
+		is.NoErr(os.WriteFile("matcher.go", source, 0644))
+	}
+	existing, err := os.ReadFile("matcher.go")
+	is.NoErr(err)
+	diff.TestString(t, string(existing), string(source))
+}
+
+// describe the result of a match for comparing
+func describe(match *enroute.Match[string], err error) string {
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	s := new(strings.Builder)
+	fmt.Fprintf(s, "route=%q path=%q value=%q\n", match.Route, match.Path, match.Value)
+	for _, slot := range match.Slots {
+		fmt.Fprintf(s, "  %s value=%q raw=%q parsed=%#v\n", slot.Key, slot.Value, slot.Raw, slot.Parsed)
+	}
+	return s.String()
+}
+
+// check that the generated matcher matches the input like the tree
+func check(t testing.TB, tree *enroute.Tree[string], input string) {
+	t.Helper()
+	expect := describe(tree.Match(input))
+	actual := describe(gentest.Match(input))
+	if expect != actual {
+		t.Fatalf("input %q\ntree:      %s\ngenerated: %s", input, expect, actual)
+	}
+}
+
+var inputs = []string{
+	"/",
+	"",
+	"//",
+	"/about",
+	"/ABOUT/",
+	"/about?q=1",
+	"/%61bout",
+	"/api/docs",
+	"/api/docs/",
+	"/api/doc",
+	"/café/bob",
+	"/CAFÉ/bob",
+	"/café/bob",
+	"/caf%C3%A9/bob",
+	"/日本/10",
+	"/%E6%97%A5%E6%9C%AC/10",
+	"/users/10",
+	"/users/alice",
+	"/users/10/edit",
+	"/users/10/settings/profile",
+	"/users/a%2Fb",
+	"/users/a%zz",
+	"/users/%",
+	"/posts/10",
+	"/posts/abc",
+	"/posts/10/comments",
+	"/posts/10/comments/20",
+	"/posts/10/2024-01-02/hello",
+	"/posts/10/2024-13-02/hello",
+	"/tokens/123e4567-e89b-12d3-a456-426614174000",
+	"/tokens/nope",
+	"/fly/sfo-lax",
+	"/fly/sfo.json",
+	"/fly/sfo",
+	"/v1",
+	"/v1.2",
+	"/v1.x",
+	"/V2",
+	"/files",
+	"/files/a/b/c",
+	"/files/a%20b",
+	"/acme/enroute/main/readme.md",
+	"/acme/enroute/tree",
+	"/acme/enroute",
+	"/hello-10",
+	"/hello-x",
+	"example.com/about",
+	"example.com",
+	"localhost/about",
+	"/a{b?c}",
+	"/Kbc",
+	"/\xff",
+}
+
+func TestMatch(t *testing.T) {
+	tree := gentest.Tree()
+	for _, input := range inputs {
+		check(t, tree, input)
+	}
+}
+
+// Pieces of paths that exercise the routes' literals, slots and delimiters
+var pieces = []string{
+	"/", "/", "/", "-", ".", "?", "{", "}", "%", "%2F", "%2f", "%41", "%zz",
+	"%C3%A9", "%E6%97%A5", "about", "api", "docs", "users", "USERS", "posts",
+	"comments", "edit", "tree", "fly", "files", "tokens", "café", "caf",
+	"é", "日本", "v", "V", "1", "42", "x", "abc", "2024-01-02",
+	"123e4567-e89b-12d3-a456-426614174000", "example.com", "K", "\xff",
+}
+
+func TestMatchRandom(t *testing.T) {
+	tree := gentest.Tree()
+	random := rand.New(rand.NewSource(1))
+	for range 20000 {
+		input := new(strings.Builder)
+		for range random.Intn(8) {
+			input.WriteString(pieces[random.Intn(len(pieces))])
+		}
+		check(t, tree, input.String())
+	}
+}
+
+func FuzzMatch(f *testing.F) {
+	for _, input := range inputs {
+		f.Add(input)
+	}
+	tree := gentest.Tree()
+	f.Fuzz(func(t *testing.T, input string) {
+		check(t, tree, input)
+	})
+}
+
+func TestLookupAllocs(t *testing.T) {
+	is := is.New(t)
+	var result gentest.Result
+	allocs := testing.AllocsPerRun(100, func() {
+		gentest.Lookup("/acme/enroute/main/readme.md", &result)
+	})
+	is.Equal(allocs, 0.0)
+	is.Equal(result.Route, "/{owner}/{repo}/{branch}/{path*}")
+	is.Equal(result.Slots(), []string{"acme", "enroute", "main", "readme.md"})
+}
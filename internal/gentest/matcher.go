@@ -0,0 +1,1709 @@
+// Code generated by enroute. DO NOT EDIT.
+
+package gentest
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/matthewmueller/enroute"
+	"github.com/matthewmueller/enroute/ast"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Result of Lookup
+type Result struct {
+	Route  string // Route that matched
+	Path   string // Normalized path that was matched
+	Value  string
+	index  int
+	slots  [4]string
+	starts [4]int // Offset of each slot in Path
+	n      int
+}
+
+// Slots returns the values of the route's slots in the normalized path in
+// order. Encoded characters are still encoded.
+func (r *Result) Slots() []string {
+	return r.slots[:r.n]
+}
+
+type route struct {
+	label string
+	value string
+	keys  []string
+	types []string // Type of each slot, empty for slots without a type
+}
+
+var routes = [...]route{
+	{"/", "/", nil, nil},
+	{"/about", "/about", nil, nil},
+	{"/api/docs", "/api/docs", nil, nil},
+	{"/café/{name}", "/café/{name}", []string{"name"}, []string{""}},
+	{"/日本/{id}", "/日本/{id}", []string{"id"}, []string{""}},
+	{"/users/{id:int}", "/users/{id:int}", []string{"id"}, []string{"int"}},
+	{"/users/{id}", "/users/{id}", []string{"id"}, []string{""}},
+	{"/users/{id}/edit", "/users/{id}/edit", []string{"id"}, []string{""}},
+	{"/users/{id}/{path*}", "/users/{id}/{path*}", []string{"id", "path"}, []string{"", ""}},
+	{"/posts/{id|^[0-9]+$}", "/posts/{id|[0-9]+}", []string{"id"}, []string{""}},
+	{"/posts/{id:int}/{day:date}/{slug}", "/posts/{id:int}/{day:date}/{slug}", []string{"id", "day", "slug"}, []string{"int", "date", ""}},
+	{"/posts/{post_id}/comments/{id?}", "/posts/{post_id}/comments/{id?}", []string{"post_id"}, []string{""}},
+	{"/posts/{post_id}/comments/{id?}", "/posts/{post_id}/comments/{id?}", []string{"post_id", "id"}, []string{"", ""}},
+	{"/tokens/{token:uuid}", "/tokens/{token:uuid}", []string{"token"}, []string{"uuid"}},
+	{"/fly/{from}-{to}", "/fly/{from}-{to}", []string{"from", "to"}, []string{"", ""}},
+	{"/fly/{from}.{format}", "/fly/{from}.{format}", []string{"from", "format"}, []string{"", ""}},
+	{"/files/{path*}", "/files/{path*}", nil, nil},
+	{"/files/{path*}", "/files/{path*}", []string{"path"}, []string{""}},
+	{"/v{major|^[0-9]+$}", "/v{major|[0-9]+}", []string{"major"}, []string{""}},
+	{"/v{major|^[0-9]+$}.{minor|^[0-9]+$}", "/v{major|[0-9]+}.{minor|[0-9]+}", []string{"major", "minor"}, []string{"", ""}},
+	{"/{slug|^[a-z]+$}-{id:int}", "/{slug|[a-z]+}-{id:int}", []string{"slug", "id"}, []string{"", "int"}},
+	{"/{owner}/{repo}/tree", "/{owner}/{repo}/tree", []string{"owner", "repo"}, []string{"", ""}},
+	{"/{owner}/{repo}/{branch}/{path*}", "/{owner}/{repo}/{branch}/{path*}", []string{"owner", "repo", "branch"}, []string{"", "", ""}},
+	{"/{owner}/{repo}/{branch}/{path*}", "/{owner}/{repo}/{branch}/{path*}", []string{"owner", "repo", "branch", "path"}, []string{"", "", "", ""}},
+}
+
+// Match the input to a route like Tree.Match
+func Match(input string) (*enroute.Match[string], error) {
+	var result Result
+	if !Lookup(input, &result) {
+		return nil, fmt.Errorf("%w for %q", enroute.ErrNoMatch, result.Path)
+	}
+	route := &routes[result.index]
+	match := &enroute.Match[string]{Route: route.label, Path: result.Path, Value: route.value}
+	// Raw values are cut from the path before it was normalized
+	path, _ := cutQuery(input)
+	original := trimTrailingSlash(path)
+	var offsets []int
+	if original != result.Path {
+		_, offsets = normalizeOffsets(original)
+	}
+	for i, key := range route.keys {
+		normalized := result.slots[i]
+		raw := normalized
+		if offsets != nil {
+			start := result.starts[i]
+			raw = original[offsets[start]:offsets[start+len(normalized)]]
+		}
+		slot := &enroute.Slot{Key: key, Value: decodeSlot(normalized), Raw: raw}
+		if route.types[i] != "" {
+			if t, ok := ast.LookupType(route.types[i]); ok {
+				value, _ := ast.Unescape(normalized)
+				slot.Parsed, _ = t.Parse(value)
+			}
+		}
+		match.Slots = append(match.Slots, slot)
+	}
+	return match, nil
+}
+
+// Lookup matches the input to a route without allocating the slots. The
+// result is filled in even when nothing matches.
+func Lookup(input string, result *Result) bool {
+	input, _ = cutQuery(input)
+	input = normalize(trimTrailingSlash(input))
+	result.Path = input
+	result.n = 0
+	_, path := splitHost(input)
+	if len(path) == 0 || path[0] != '/' {
+		return false
+	}
+	return match0(path, result)
+}
+
+// match0 matches /
+func match0(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if path[0] != '/' {
+			goto fail
+		}
+		path = path[1:]
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/", "/", 0
+		return true
+	}
+	switch path[0] {
+	case '%':
+		if match1(path, r) || match4(path, r) || match5(path, r) || match6(path, r) || match12(path, r) || match17(path, r) || match18(path, r) || match24(path, r) {
+			return true
+		}
+	case 'A', 'a':
+		if match1(path, r) {
+			return true
+		}
+	case 'C', 'c':
+		if match4(path, r) {
+			return true
+		}
+	case 'F', 'f':
+		if match18(path, r) {
+			return true
+		}
+	case 'P', 'p':
+		if match12(path, r) {
+			return true
+		}
+	case 'T', 't':
+		if match17(path, r) {
+			return true
+		}
+	case 'U', 'u':
+		if match6(path, r) {
+			return true
+		}
+	case 'V', 'v':
+		if match24(path, r) {
+			return true
+		}
+	case 0xe6:
+		if match5(path, r) {
+			return true
+		}
+	}
+	if match26(path, r) || match27(path, r) {
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match1 matches a
+func match1(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if len(path) >= 1 && strings.EqualFold(path[:1], "a") {
+			path = path[1:]
+		} else if prefix, width, ok := decodePrefix(path, 1); ok && strings.EqualFold(prefix, "a") {
+			path = path[width:]
+		} else {
+			goto fail
+		}
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	switch path[0] {
+	case '%':
+		if match2(path, r) || match3(path, r) {
+			return true
+		}
+	case 'B', 'b':
+		if match2(path, r) {
+			return true
+		}
+	case 'P', 'p':
+		if match3(path, r) {
+			return true
+		}
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match2 matches bout
+func match2(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if len(path) >= 4 && strings.EqualFold(path[:4], "bout") {
+			path = path[4:]
+		} else if prefix, width, ok := decodePrefix(path, 4); ok && strings.EqualFold(prefix, "bout") {
+			path = path[width:]
+		} else {
+			goto fail
+		}
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/about", "/about", 1
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match3 matches pi/docs
+func match3(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if len(path) >= 2 && strings.EqualFold(path[:2], "pi") {
+			path = path[2:]
+		} else if prefix, width, ok := decodePrefix(path, 2); ok && strings.EqualFold(prefix, "pi") {
+			path = path[width:]
+		} else {
+			goto fail
+		}
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if path[0] != '/' {
+			goto fail
+		}
+		path = path[1:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if len(path) >= 4 && strings.EqualFold(path[:4], "docs") {
+			path = path[4:]
+		} else if prefix, width, ok := decodePrefix(path, 4); ok && strings.EqualFold(prefix, "docs") {
+			path = path[width:]
+		} else {
+			goto fail
+		}
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/api/docs", "/api/docs", 2
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match4 matches café/{name}
+func match4(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if len(path) >= 5 && strings.EqualFold(path[:5], "café") {
+			path = path[5:]
+		} else if prefix, width, ok := decodePrefix(path, 5); ok && strings.EqualFold(prefix, "café") {
+			path = path[width:]
+		} else {
+			goto fail
+		}
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if path[0] != '/' {
+			goto fail
+		}
+		path = path[1:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		i := strings.IndexByte(path, '/')
+		if i < 0 {
+			i = len(path)
+		}
+		if i == 0 {
+			goto fail
+		}
+		value := path[:i]
+		if !accept(value) {
+			goto fail
+		}
+		r.slots[r.n] = value
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = path[i:]
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/café/{name}", "/café/{name}", 3
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match5 matches 日本/{id}
+func match5(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if len(path) >= 6 && strings.EqualFold(path[:6], "日本") {
+			path = path[6:]
+		} else if prefix, width, ok := decodePrefix(path, 6); ok && strings.EqualFold(prefix, "日本") {
+			path = path[width:]
+		} else {
+			goto fail
+		}
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if path[0] != '/' {
+			goto fail
+		}
+		path = path[1:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		i := strings.IndexByte(path, '/')
+		if i < 0 {
+			i = len(path)
+		}
+		if i == 0 {
+			goto fail
+		}
+		value := path[:i]
+		if !accept(value) {
+			goto fail
+		}
+		r.slots[r.n] = value
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = path[i:]
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/日本/{id}", "/日本/{id}", 4
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match6 matches users/
+func match6(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if len(path) >= 5 && strings.EqualFold(path[:5], "users") {
+			path = path[5:]
+		} else if prefix, width, ok := decodePrefix(path, 5); ok && strings.EqualFold(prefix, "users") {
+			path = path[width:]
+		} else {
+			goto fail
+		}
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if path[0] != '/' {
+			goto fail
+		}
+		path = path[1:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	if match7(path, r) || match8(path, r) {
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match7 matches {id:int}
+func match7(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		i := strings.IndexByte(path, '/')
+		if i < 0 {
+			i = len(path)
+		}
+		if i == 0 {
+			goto fail
+		}
+		value := path[:i]
+		if !accept(value) {
+			goto fail
+		}
+		if !parses("int", value) {
+			goto fail
+		}
+		r.slots[r.n] = value
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = path[i:]
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/users/{id:int}", "/users/{id:int}", 5
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match8 matches {id}
+func match8(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		i := strings.IndexByte(path, '/')
+		if i < 0 {
+			i = len(path)
+		}
+		if i == 0 {
+			goto fail
+		}
+		value := path[:i]
+		if !accept(value) {
+			goto fail
+		}
+		r.slots[r.n] = value
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = path[i:]
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/users/{id}", "/users/{id}", 6
+		return true
+	}
+	switch path[0] {
+	case '/':
+		if match9(path, r) {
+			return true
+		}
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match9 matches /
+func match9(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if path[0] != '/' {
+			goto fail
+		}
+		path = path[1:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	switch path[0] {
+	case '%', 'E', 'e':
+		if match10(path, r) {
+			return true
+		}
+	}
+	if match11(path, r) {
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match10 matches edit
+func match10(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if len(path) >= 4 && strings.EqualFold(path[:4], "edit") {
+			path = path[4:]
+		} else if prefix, width, ok := decodePrefix(path, 4); ok && strings.EqualFold(prefix, "edit") {
+			path = path[width:]
+		} else {
+			goto fail
+		}
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/users/{id}/edit", "/users/{id}/edit", 7
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match11 matches {path*}
+func match11(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if !accept(path) {
+			goto fail
+		}
+		r.slots[r.n] = path
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = ""
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/users/{id}/{path*}", "/users/{id}/{path*}", 8
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match12 matches posts/
+func match12(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if len(path) >= 5 && strings.EqualFold(path[:5], "posts") {
+			path = path[5:]
+		} else if prefix, width, ok := decodePrefix(path, 5); ok && strings.EqualFold(prefix, "posts") {
+			path = path[width:]
+		} else {
+			goto fail
+		}
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if path[0] != '/' {
+			goto fail
+		}
+		path = path[1:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	if match13(path, r) || match14(path, r) || match15(path, r) {
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match13 matches {id|^[0-9]+$}
+func match13(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		i := strings.IndexByte(path, '/')
+		if i < 0 {
+			i = len(path)
+		}
+		if i == 0 {
+			goto fail
+		}
+		value := path[:i]
+		if !accept(value) {
+			goto fail
+		}
+		if decoded, _ := ast.Unescape(value); !pattern0.MatchString(decoded) {
+			goto fail
+		}
+		r.slots[r.n] = value
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = path[i:]
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/posts/{id|^[0-9]+$}", "/posts/{id|[0-9]+}", 9
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match14 matches {id:int}/{day:date}/{slug}
+func match14(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		i := strings.IndexByte(path, '/')
+		if i < 0 {
+			i = len(path)
+		}
+		if i == 0 {
+			goto fail
+		}
+		value := path[:i]
+		if !accept(value) {
+			goto fail
+		}
+		if !parses("int", value) {
+			goto fail
+		}
+		r.slots[r.n] = value
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = path[i:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if path[0] != '/' {
+			goto fail
+		}
+		path = path[1:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		i := strings.IndexByte(path, '/')
+		if i < 0 {
+			i = len(path)
+		}
+		if i == 0 {
+			goto fail
+		}
+		value := path[:i]
+		if !accept(value) {
+			goto fail
+		}
+		if !parses("date", value) {
+			goto fail
+		}
+		r.slots[r.n] = value
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = path[i:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if path[0] != '/' {
+			goto fail
+		}
+		path = path[1:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		i := strings.IndexByte(path, '/')
+		if i < 0 {
+			i = len(path)
+		}
+		if i == 0 {
+			goto fail
+		}
+		value := path[:i]
+		if !accept(value) {
+			goto fail
+		}
+		r.slots[r.n] = value
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = path[i:]
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/posts/{id:int}/{day:date}/{slug}", "/posts/{id:int}/{day:date}/{slug}", 10
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match15 matches {post_id}/comments
+func match15(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		i := strings.IndexByte(path, '/')
+		if i < 0 {
+			i = len(path)
+		}
+		if i == 0 {
+			goto fail
+		}
+		value := path[:i]
+		if !accept(value) {
+			goto fail
+		}
+		r.slots[r.n] = value
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = path[i:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if path[0] != '/' {
+			goto fail
+		}
+		path = path[1:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if len(path) >= 8 && strings.EqualFold(path[:8], "comments") {
+			path = path[8:]
+		} else if prefix, width, ok := decodePrefix(path, 8); ok && strings.EqualFold(prefix, "comments") {
+			path = path[width:]
+		} else {
+			goto fail
+		}
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/posts/{post_id}/comments/{id?}", "/posts/{post_id}/comments/{id?}", 11
+		return true
+	}
+	switch path[0] {
+	case '/':
+		if match16(path, r) {
+			return true
+		}
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match16 matches /{id}
+func match16(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if path[0] != '/' {
+			goto fail
+		}
+		path = path[1:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		i := strings.IndexByte(path, '/')
+		if i < 0 {
+			i = len(path)
+		}
+		if i == 0 {
+			goto fail
+		}
+		value := path[:i]
+		if !accept(value) {
+			goto fail
+		}
+		r.slots[r.n] = value
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = path[i:]
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/posts/{post_id}/comments/{id?}", "/posts/{post_id}/comments/{id?}", 12
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match17 matches tokens/{token:uuid}
+func match17(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if len(path) >= 6 && strings.EqualFold(path[:6], "tokens") {
+			path = path[6:]
+		} else if prefix, width, ok := decodePrefix(path, 6); ok && strings.EqualFold(prefix, "tokens") {
+			path = path[width:]
+		} else {
+			goto fail
+		}
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if path[0] != '/' {
+			goto fail
+		}
+		path = path[1:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		i := strings.IndexByte(path, '/')
+		if i < 0 {
+			i = len(path)
+		}
+		if i == 0 {
+			goto fail
+		}
+		value := path[:i]
+		if !accept(value) {
+			goto fail
+		}
+		if !parses("uuid", value) {
+			goto fail
+		}
+		r.slots[r.n] = value
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = path[i:]
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/tokens/{token:uuid}", "/tokens/{token:uuid}", 13
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match18 matches f
+func match18(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if len(path) >= 1 && strings.EqualFold(path[:1], "f") {
+			path = path[1:]
+		} else if prefix, width, ok := decodePrefix(path, 1); ok && strings.EqualFold(prefix, "f") {
+			path = path[width:]
+		} else {
+			goto fail
+		}
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	switch path[0] {
+	case '%':
+		if match19(path, r) || match22(path, r) {
+			return true
+		}
+	case 'I', 'i':
+		if match22(path, r) {
+			return true
+		}
+	case 'L', 'l':
+		if match19(path, r) {
+			return true
+		}
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match19 matches ly/{from}
+func match19(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if len(path) >= 2 && strings.EqualFold(path[:2], "ly") {
+			path = path[2:]
+		} else if prefix, width, ok := decodePrefix(path, 2); ok && strings.EqualFold(prefix, "ly") {
+			path = path[width:]
+		} else {
+			goto fail
+		}
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if path[0] != '/' {
+			goto fail
+		}
+		path = path[1:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		i := strings.IndexAny(path, "-./")
+		if i < 0 {
+			i = len(path)
+		}
+		if i == 0 {
+			goto fail
+		}
+		value := path[:i]
+		if !accept(value) {
+			goto fail
+		}
+		r.slots[r.n] = value
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = path[i:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	switch path[0] {
+	case '%':
+		if match20(path, r) || match21(path, r) {
+			return true
+		}
+	case '-':
+		if match20(path, r) {
+			return true
+		}
+	case '.':
+		if match21(path, r) {
+			return true
+		}
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match20 matches -{to}
+func match20(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if len(path) >= 1 && strings.EqualFold(path[:1], "-") {
+			path = path[1:]
+		} else if prefix, width, ok := decodePrefix(path, 1); ok && strings.EqualFold(prefix, "-") {
+			path = path[width:]
+		} else {
+			goto fail
+		}
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		i := strings.IndexByte(path, '/')
+		if i < 0 {
+			i = len(path)
+		}
+		if i == 0 {
+			goto fail
+		}
+		value := path[:i]
+		if !accept(value) {
+			goto fail
+		}
+		r.slots[r.n] = value
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = path[i:]
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/fly/{from}-{to}", "/fly/{from}-{to}", 14
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match21 matches .{format}
+func match21(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if len(path) >= 1 && strings.EqualFold(path[:1], ".") {
+			path = path[1:]
+		} else if prefix, width, ok := decodePrefix(path, 1); ok && strings.EqualFold(prefix, ".") {
+			path = path[width:]
+		} else {
+			goto fail
+		}
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		i := strings.IndexByte(path, '/')
+		if i < 0 {
+			i = len(path)
+		}
+		if i == 0 {
+			goto fail
+		}
+		value := path[:i]
+		if !accept(value) {
+			goto fail
+		}
+		r.slots[r.n] = value
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = path[i:]
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/fly/{from}.{format}", "/fly/{from}.{format}", 15
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match22 matches iles
+func match22(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if len(path) >= 4 && strings.EqualFold(path[:4], "iles") {
+			path = path[4:]
+		} else if prefix, width, ok := decodePrefix(path, 4); ok && strings.EqualFold(prefix, "iles") {
+			path = path[width:]
+		} else {
+			goto fail
+		}
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/files/{path*}", "/files/{path*}", 16
+		return true
+	}
+	switch path[0] {
+	case '/':
+		if match23(path, r) {
+			return true
+		}
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match23 matches /{path*}
+func match23(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if path[0] != '/' {
+			goto fail
+		}
+		path = path[1:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if !accept(path) {
+			goto fail
+		}
+		r.slots[r.n] = path
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = ""
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/files/{path*}", "/files/{path*}", 17
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match24 matches v{major|^[0-9]+$}
+func match24(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if len(path) >= 1 && strings.EqualFold(path[:1], "v") {
+			path = path[1:]
+		} else if prefix, width, ok := decodePrefix(path, 1); ok && strings.EqualFold(prefix, "v") {
+			path = path[width:]
+		} else {
+			goto fail
+		}
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		i := strings.IndexAny(path, "./")
+		if i < 0 {
+			i = len(path)
+		}
+		if i == 0 {
+			goto fail
+		}
+		value := path[:i]
+		if !accept(value) {
+			goto fail
+		}
+		if decoded, _ := ast.Unescape(value); !pattern0.MatchString(decoded) {
+			goto fail
+		}
+		r.slots[r.n] = value
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = path[i:]
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/v{major|^[0-9]+$}", "/v{major|[0-9]+}", 18
+		return true
+	}
+	switch path[0] {
+	case '%', '.':
+		if match25(path, r) {
+			return true
+		}
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match25 matches .{minor|^[0-9]+$}
+func match25(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if len(path) >= 1 && strings.EqualFold(path[:1], ".") {
+			path = path[1:]
+		} else if prefix, width, ok := decodePrefix(path, 1); ok && strings.EqualFold(prefix, ".") {
+			path = path[width:]
+		} else {
+			goto fail
+		}
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		i := strings.IndexByte(path, '/')
+		if i < 0 {
+			i = len(path)
+		}
+		if i == 0 {
+			goto fail
+		}
+		value := path[:i]
+		if !accept(value) {
+			goto fail
+		}
+		if decoded, _ := ast.Unescape(value); !pattern0.MatchString(decoded) {
+			goto fail
+		}
+		r.slots[r.n] = value
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = path[i:]
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/v{major|^[0-9]+$}.{minor|^[0-9]+$}", "/v{major|[0-9]+}.{minor|[0-9]+}", 19
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match26 matches {slug|^[a-z]+$}-{id:int}
+func match26(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		i := strings.IndexAny(path, "-/")
+		if i < 0 {
+			i = len(path)
+		}
+		if i == 0 {
+			goto fail
+		}
+		value := path[:i]
+		if !accept(value) {
+			goto fail
+		}
+		if decoded, _ := ast.Unescape(value); !pattern1.MatchString(decoded) {
+			goto fail
+		}
+		r.slots[r.n] = value
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = path[i:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if len(path) >= 1 && strings.EqualFold(path[:1], "-") {
+			path = path[1:]
+		} else if prefix, width, ok := decodePrefix(path, 1); ok && strings.EqualFold(prefix, "-") {
+			path = path[width:]
+		} else {
+			goto fail
+		}
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		i := strings.IndexByte(path, '/')
+		if i < 0 {
+			i = len(path)
+		}
+		if i == 0 {
+			goto fail
+		}
+		value := path[:i]
+		if !accept(value) {
+			goto fail
+		}
+		if !parses("int", value) {
+			goto fail
+		}
+		r.slots[r.n] = value
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = path[i:]
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/{slug|^[a-z]+$}-{id:int}", "/{slug|[a-z]+}-{id:int}", 20
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match27 matches {owner}/{repo}/
+func match27(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		i := strings.IndexByte(path, '/')
+		if i < 0 {
+			i = len(path)
+		}
+		if i == 0 {
+			goto fail
+		}
+		value := path[:i]
+		if !accept(value) {
+			goto fail
+		}
+		r.slots[r.n] = value
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = path[i:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if path[0] != '/' {
+			goto fail
+		}
+		path = path[1:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		i := strings.IndexByte(path, '/')
+		if i < 0 {
+			i = len(path)
+		}
+		if i == 0 {
+			goto fail
+		}
+		value := path[:i]
+		if !accept(value) {
+			goto fail
+		}
+		r.slots[r.n] = value
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = path[i:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if path[0] != '/' {
+			goto fail
+		}
+		path = path[1:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	switch path[0] {
+	case '%', 'T', 't':
+		if match28(path, r) {
+			return true
+		}
+	}
+	if match29(path, r) {
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match28 matches tree
+func match28(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if len(path) >= 4 && strings.EqualFold(path[:4], "tree") {
+			path = path[4:]
+		} else if prefix, width, ok := decodePrefix(path, 4); ok && strings.EqualFold(prefix, "tree") {
+			path = path[width:]
+		} else {
+			goto fail
+		}
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/{owner}/{repo}/tree", "/{owner}/{repo}/tree", 21
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match29 matches {branch}
+func match29(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		i := strings.IndexByte(path, '/')
+		if i < 0 {
+			i = len(path)
+		}
+		if i == 0 {
+			goto fail
+		}
+		value := path[:i]
+		if !accept(value) {
+			goto fail
+		}
+		r.slots[r.n] = value
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = path[i:]
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/{owner}/{repo}/{branch}/{path*}", "/{owner}/{repo}/{branch}/{path*}", 22
+		return true
+	}
+	switch path[0] {
+	case '/':
+		if match30(path, r) {
+			return true
+		}
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// match30 matches /{path*}
+func match30(path string, r *Result) bool {
+	n := r.n
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if path[0] != '/' {
+			goto fail
+		}
+		path = path[1:]
+	}
+	if len(path) == 0 {
+		goto fail
+	}
+	{
+		if !accept(path) {
+			goto fail
+		}
+		r.slots[r.n] = path
+		r.starts[r.n] = len(r.Path) - len(path)
+		r.n++
+		path = ""
+	}
+	if len(path) == 0 {
+		r.Route, r.Value, r.index = "/{owner}/{repo}/{branch}/{path*}", "/{owner}/{repo}/{branch}/{path*}", 23
+		return true
+	}
+fail:
+	r.n = n
+	return false
+}
+
+// parses is true if the slot's type parses its raw value
+func parses(name, raw string) bool {
+	t, ok := ast.LookupType(name)
+	if !ok {
+		return false
+	}
+	value, _ := ast.Unescape(raw)
+	_, err := t.Parse(value)
+	return err == nil
+}
+
+// decodePrefix decodes the start of the path until it's n bytes long. It
+// returns the decoded prefix and how much of the path it took.
+func decodePrefix(path string, n int) (prefix string, width int, ok bool) {
+	if len(path) < n {
+		return "", 0, false
+	}
+	// Fast path for prefixes without any percent-encoding
+	if strings.IndexByte(path[:n], '%') < 0 {
+		return path[:n], n, true
+	}
+	decoded := make([]byte, 0, n)
+	for len(decoded) < n {
+		if width >= len(path) {
+			return "", 0, false
+		}
+		if path[width] != '%' {
+			decoded = append(decoded, path[width])
+			width++
+			continue
+		}
+		if width+2 >= len(path) || !isHex(path[width+1]) || !isHex(path[width+2]) {
+			return "", 0, false
+		}
+		decoded = append(decoded, unhex(path[width+1])<<4|unhex(path[width+2]))
+		width += 3
+	}
+	return string(decoded), width, true
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+// indexEncodedSlash returns the index of the first encoded slash or -1
+func indexEncodedSlash(raw string) int {
+	for i := 0; i+2 < len(raw); i++ {
+		if raw[i] == '%' && raw[i+1] == '2' && (raw[i+2] == 'F' || raw[i+2] == 'f') {
+			return i
+		}
+	}
+	return -1
+}
+
+// cutQuery cuts the input around the first question mark that's not within a
+// slot like {id?}
+func cutQuery(input string) (path, query string) {
+	depth := 0
+	for i := 0; i < len(input); i++ {
+		switch input[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '?':
+			if depth == 0 {
+				return input[:i], input[i+1:]
+			}
+		}
+	}
+	return input, ""
+}
+
+// trimTrailingSlash strips any trailing slash (e.g. /users/ => /users),
+// keeping the query if there is one
+func trimTrailingSlash(input string) string {
+	path, query := cutQuery(input)
+	path = strings.TrimRight(path, "/")
+	if len(path) == 0 {
+		path = "/"
+	}
+	if len(query) > 0 {
+		return path + "?" + query
+	}
+	return path
+}
+
+// normalize the path to NFC, so it matches routes written in any unicode form.
+// Percent-encoded unicode characters are decoded to be normalized too, while
+// encoded ASCII characters like %2F stay encoded.
+func normalize(path string) string {
+	normalized, _ := normalizeOffsets(path)
+	return normalized
+}
+
+// normalizeOffsets normalizes the path like normalize. It also returns the
+// offset in the path of each byte in the normalized path, followed by the
+// length of the path, so parts of the normalized path can be mapped back to
+// the path. The offsets are nil if the path didn't change.
+func normalizeOffsets(path string) (string, []int) {
+	decoded := path
+	var offsets []int
+	if strings.IndexByte(path, '%') >= 0 {
+		b := make([]byte, 0, len(path))
+		offsets = make([]int, 0, len(path)+1)
+		for i := 0; i < len(path); i++ {
+			offsets = append(offsets, i)
+			if path[i] == '%' && i+2 < len(path) {
+				if c, err := strconv.ParseUint(path[i+1:i+3], 16, 8); err == nil && c >= utf8.RuneSelf {
+					b = append(b, byte(c))
+					i += 2
+					continue
+				}
+			}
+			b = append(b, path[i])
+		}
+		if len(b) == len(path) {
+			// Nothing was decoded
+			offsets = nil
+		} else {
+			decoded = string(b)
+		}
+	}
+	if isASCII(decoded) || norm.NFC.IsNormalString(decoded) {
+		if offsets == nil {
+			return decoded, nil
+		}
+		return decoded, append(offsets, len(path))
+	}
+	if offsets == nil {
+		offsets = make([]int, len(decoded))
+		for i := range offsets {
+			offsets[i] = i
+		}
+	}
+	// Normalize segment by segment to keep track of where each segment started
+	var iter norm.Iter
+	iter.InitString(norm.NFC, decoded)
+	b := make([]byte, 0, len(decoded))
+	normalized := make([]int, 0, len(decoded)+1)
+	for !iter.Done() {
+		start := iter.Pos()
+		segment := iter.Next()
+		for range segment {
+			normalized = append(normalized, offsets[start])
+		}
+		b = append(b, segment...)
+	}
+	return string(b), append(normalized, len(path))
+}
+
+// isASCII is true if the path is ASCII, which is always normalized
+func isASCII(path string) bool {
+	for i := 0; i < len(path); i++ {
+		if path[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// splitHost splits the input into its host and path. Inputs have a host when
+// there's a dot before the first slash (e.g. acme.example.com/dashboard).
+func splitHost(input string) (host, path string) {
+	if strings.HasPrefix(input, "/") {
+		return "", input
+	}
+	host, path = input, "/"
+	if i := strings.IndexByte(input, '/'); i >= 0 {
+		host, path = input[:i], input[i:]
+	}
+	if !strings.Contains(host, ".") {
+		return "", input
+	}
+	return host, path
+}
+
+// accept is true if the slot's raw value is properly encoded and allowed by
+// the encoded slash policy
+func accept(raw string) bool {
+	if strings.IndexByte(raw, '%') < 0 {
+		return true
+	}
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '%' {
+			continue
+		}
+		if i+2 >= len(raw) || !isHex(raw[i+1]) || !isHex(raw[i+2]) {
+			return false
+		}
+		i += 2
+	}
+	return true
+}
+
+// decodeSlot decodes the slot's raw value
+func decodeSlot(raw string) string {
+	value, _ := ast.Unescape(raw)
+	return value
+}
+
+var (
+	pattern0 = regexp.MustCompile("^[0-9]+$")
+	pattern1 = regexp.MustCompile("^[a-z]+$")
+)
@@ -12,17 +12,30 @@ import (
 
 type state = func(l *Lexer) token.Type
 
-func New(input string) *Lexer {
+// Option configures the lexer
+type Option func(l *Lexer)
+
+// AllowUppercase allows uppercase letters in paths
+func AllowUppercase() Option {
+	return func(l *Lexer) {
+		l.upper = true
+	}
+}
+
+func New(input string, options ...Option) *Lexer {
 	l := &Lexer{
 		input:  input,
 		states: []state{initialState},
 	}
+	for _, option := range options {
+		option(l)
+	}
 	l.step()
 	return l
 }
 
-func Lex(input string) []token.Token {
-	l := New(input)
+func Lex(input string, options ...Option) []token.Token {
+	l := New(input, options...)
 	var tokens []token.Token
 	for l.Next() {
 		tokens = append(tokens, l.Token)
@@ -31,8 +44,8 @@ func Lex(input string) []token.Token {
 }
 
 // Print the input as tokens
-func Print(input string) string {
-	tokens := Lex(input)
+func Print(input string, options ...Option) string {
+	tokens := Lex(input, options...)
 	stoken := make([]string, len(tokens))
 	for i, token := range tokens {
 		stoken[i] = token.String()
@@ -48,6 +61,7 @@ type Lexer struct {
 	cp    rune        // Code point being considered
 	next  int         // Index to the next rune to be considered
 	err   string      // Error message for an error token
+	upper bool        // Allow uppercase letters in paths
 
 	states []state // Stack of states
 	peaked []token.Token
@@ -102,6 +116,15 @@ func (l *Lexer) step() {
 	l.next += width
 }
 
+// peek at the rune after the current one
+func (l *Lexer) peek() rune {
+	r, width := utf8.DecodeRuneInString(l.input[l.next:])
+	if width == 0 {
+		return eof
+	}
+	return r
+}
+
 func (l *Lexer) text() string {
 	return l.input[l.start:l.end]
 }
@@ -140,11 +163,68 @@ func initialState(l *Lexer) token.Type {
 		l.pushState(pathState)
 		return token.Slash
 	default:
+		if l.isHost() {
+			l.pushState(hostState)
+			return hostState(l)
+		}
 		l.stepUntil('/')
 		return l.errorf(`path must start with a slash /`)
 	}
 }
 
+// isHost is true if the route starts with a host like {tenant}.example.com,
+// which has a dot before the first slash
+func (l *Lexer) isHost() bool {
+	depth := 0
+	for _, r := range l.input[l.end:] {
+		switch {
+		case r == '{':
+			depth++
+		case r == '}':
+			depth--
+		case r == '/' && depth == 0:
+			return false
+		case r == '.' && depth == 0:
+			return true
+		}
+	}
+	return false
+}
+
+func hostState(l *Lexer) token.Type {
+	switch {
+	case l.cp == eof:
+		l.popState()
+		return token.End
+	case l.cp == '/':
+		l.step()
+		l.popState()
+		l.pushState(pathState)
+		return token.Slash
+	case l.cp == '.':
+		l.step()
+		return token.Dot
+	case l.cp == '{':
+		l.step()
+		l.pushState(slotState)
+		return token.OpenCurly
+	case isHostChar(l.cp):
+		l.step()
+		for isHostChar(l.cp) {
+			l.step()
+		}
+		return token.Host
+	}
+	// Skip forward for the error
+	for {
+		l.step()
+		if l.cp == eof || l.cp == '/' || l.cp == '.' || l.cp == '{' || isHostChar(l.cp) {
+			break
+		}
+	}
+	return l.errorf("unexpected character '%s' in host", l.text())
+}
+
 func pathState(l *Lexer) token.Type {
 	switch {
 	case l.cp == eof:
@@ -157,9 +237,14 @@ func pathState(l *Lexer) token.Type {
 		l.step()
 		l.pushState(slotState)
 		return token.OpenCurly
-	case isPathChar(l.cp):
+	case l.cp == '?' && l.isPathChar(l.peek()):
 		l.step()
-		for isPathChar(l.cp) {
+		l.popState()
+		l.pushState(queryKeyState)
+		return token.Question
+	case l.isPathChar(l.cp):
+		l.step()
+		for l.isPathChar(l.cp) {
 			l.step()
 		}
 		return token.Path
@@ -167,13 +252,67 @@ func pathState(l *Lexer) token.Type {
 	// Skip forward for the error
 	for {
 		l.step()
-		if l.cp == eof || l.cp == '/' || l.cp == '{' || isPathChar(l.cp) {
+		if l.cp == eof || l.cp == '/' || l.cp == '{' || l.isPathChar(l.cp) {
 			break
 		}
 	}
 	return l.errorf("unexpected character '%s' in path", l.text())
 }
 
+func queryKeyState(l *Lexer) token.Type {
+	switch {
+	case l.cp == eof:
+		l.popState()
+		return token.End
+	case l.isPathChar(l.cp):
+		l.step()
+		for l.isPathChar(l.cp) {
+			l.step()
+		}
+		l.popState()
+		l.pushState(queryValueState)
+		return token.Key
+	}
+	// Skip forward for the error
+	l.step()
+	for l.cp != eof && l.cp != '&' && !l.isPathChar(l.cp) {
+		l.step()
+	}
+	return l.errorf("unexpected character '%s' in query", l.text())
+}
+
+func queryValueState(l *Lexer) token.Type {
+	switch {
+	case l.cp == eof:
+		l.popState()
+		return token.End
+	case l.cp == '=':
+		l.step()
+		return token.Equal
+	case l.cp == '&':
+		l.step()
+		l.popState()
+		l.pushState(queryKeyState)
+		return token.Amp
+	case l.cp == '{':
+		l.step()
+		l.pushState(slotState)
+		return token.OpenCurly
+	case isQueryChar(l.cp):
+		l.step()
+		for isQueryChar(l.cp) {
+			l.step()
+		}
+		return token.Value
+	}
+	// Skip forward for the error
+	l.step()
+	for l.cp != eof && l.cp != '&' && l.cp != '{' && !isQueryChar(l.cp) {
+		l.step()
+	}
+	return l.errorf("unexpected character '%s' in query", l.text())
+}
+
 func slotState(l *Lexer) token.Type {
 	switch {
 	case l.cp == eof:
@@ -216,6 +355,11 @@ func slotModifierState(l *Lexer) token.Type {
 		l.step()
 		l.pushState(slotRegexpState)
 		return token.Pipe
+	case l.cp == ':':
+		l.step()
+		l.popState()
+		l.pushState(slotTypeState)
+		return token.Colon
 	default:
 		l.step()
 		l.popState()
@@ -224,6 +368,31 @@ func slotModifierState(l *Lexer) token.Type {
 	}
 }
 
+func slotTypeState(l *Lexer) token.Type {
+	switch {
+	case l.cp == eof:
+		l.popState()
+		return l.errorf("unclosed slot")
+	case l.cp == '}':
+		l.popState()
+		l.pushState(slotCloseState)
+		return l.errorf("missing type in slot")
+	case isSlotChar(l.cp):
+		l.step()
+		for isSlotChar(l.cp) {
+			l.step()
+		}
+		l.popState()
+		l.pushState(slotCloseState)
+		return token.TypeName
+	default:
+		l.step()
+		l.popState()
+		l.pushState(slotCloseState)
+		return l.errorf("slot type can't start with '%s'", l.text())
+	}
+}
+
 func slotCloseState(l *Lexer) token.Type {
 	switch l.cp {
 	case eof:
@@ -265,8 +434,10 @@ loop:
 	return token.End
 }
 
+// isLowerLetter is true for lowercase letters and letters without a case,
+// like the letters in 日本
 func isLowerLetter(r rune) bool {
-	return unicode.IsLetter(r) && unicode.IsLower(r)
+	return unicode.IsLetter(r) && !unicode.IsUpper(r) && !unicode.IsTitle(r)
 }
 
 func isNumber(r rune) bool {
@@ -297,6 +468,18 @@ func isPathChar(r rune) bool {
 	return isLowerLetter(r) || isNumber(r) || isDash(r) || isUnderscore(r) || isPeriod(r)
 }
 
+func (l *Lexer) isPathChar(r rune) bool {
+	return isPathChar(r) || (l.upper && unicode.IsLetter(r))
+}
+
+func isHostChar(r rune) bool {
+	return isLowerLetter(r) || isNumber(r) || isDash(r)
+}
+
+func isQueryChar(r rune) bool {
+	return unicode.IsLetter(r) || isNumber(r) || isDash(r) || isUnderscore(r) || isPeriod(r)
+}
+
 func isSlotChar(r rune) bool {
 	return isLowerAlpha(r) || isDigit(r) || isUnderscore(r)
 }
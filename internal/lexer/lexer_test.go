@@ -103,3 +103,57 @@ func TestAll(t *testing.T) {
 	equal(t, "/{sloT}", `/ { slot:"slo" error:"invalid character 'T' in slot" }`)
 	equal(t, "/{sloT}/", `/ { slot:"slo" error:"invalid character 'T' in slot" } /`)
 }
+
+func TestTypedSlot(t *testing.T) {
+	equal(t, "/{id:int}", `/ { slot:"id" : type:"int" }`)
+	equal(t, "/users/{id:uuid}/edit", `/ path:"users" / { slot:"id" : type:"uuid" } / path:"edit"`)
+	equal(t, "/{from:date}-{to:date}", `/ { slot:"from" : type:"date" } path:"-" { slot:"to" : type:"date" }`)
+	equal(t, "/{id:}", `/ { slot:"id" : error:"missing type in slot" }`)
+	equal(t, "/{id:int", `/ { slot:"id" : type:"int" error:"unclosed slot"`)
+	equal(t, "/{id:", `/ { slot:"id" : error:"unclosed slot"`)
+	equal(t, "/{id:Int}", `/ { slot:"id" : error:"slot type can't start with 'I'" error:"expected '}' but got 'nt'" }`)
+	equal(t, "/{id:int?}", `/ { slot:"id" : type:"int" error:"expected '}' but got '?'" }`)
+}
+
+func TestAllowUppercase(t *testing.T) {
+	test := func(input, expected string) {
+		t.Helper()
+		t.Run(input, func(t *testing.T) {
+			t.Helper()
+			diff.TestString(t, expected, lexer.Print(input, lexer.AllowUppercase()))
+		})
+	}
+	test("/Explore", `/ path:"Explore"`)
+	test("/API/Docs", `/ path:"API" / path:"Docs"`)
+	test("/API/{id}.JSON", `/ path:"API" / { slot:"id" } path:".JSON"`)
+	test("/Café", `/ path:"Café"`)
+	test("/{Slot}", `/ { error:"slot can't start with 'S'" slot:"lot" }`)
+	test("/A B", `/ path:"A" error:"unexpected character ' ' in path" path:"B"`)
+}
+
+func TestUnicode(t *testing.T) {
+	equal(t, "/日本/{id}", `/ path:"日本" / { slot:"id" }`)
+	equal(t, "/café/{name}", `/ path:"café" / { slot:"name" }`)
+	equal(t, "/{from}の{to}", `/ { slot:"from" } path:"の" { slot:"to" }`)
+	equal(t, "/Ωmega", `/ error:"unexpected character 'Ω' in path" path:"mega"`)
+}
+
+func TestHost(t *testing.T) {
+	equal(t, "example.com", `host:"example" . host:"com"`)
+	equal(t, "example.com/", `host:"example" . host:"com" /`)
+	equal(t, "{tenant}.example.com/dashboard", `{ slot:"tenant" } . host:"example" . host:"com" / path:"dashboard"`)
+	equal(t, "{tenant|[a-z]+}.example.com/{id}", `{ slot:"tenant" | regexp:"[a-z]+" } . host:"example" . host:"com" / { slot:"id" }`)
+	equal(t, "{region}-{id:int}.example.com/", `{ slot:"region" } host:"-" { slot:"id" : type:"int" } . host:"example" . host:"com" /`)
+	equal(t, "Example.com/", `error:"unexpected character 'E' in host" host:"xample" . host:"com" /`)
+	equal(t, "ex_ample.com/", `host:"ex" error:"unexpected character '_' in host" host:"ample" . host:"com" /`)
+	equal(t, "example/a.b", `error:"path must start with a slash /" / path:"a.b"`)
+}
+
+func TestQuery(t *testing.T) {
+	equal(t, "/search?q={query}&page={page?}", `/ path:"search" ? key:"q" = { slot:"query" } & key:"page" = { slot:"page" ? }`)
+	equal(t, "/search?type=post", `/ path:"search" ? key:"type" = value:"post"`)
+	equal(t, "/search?debug", `/ path:"search" ? key:"debug"`)
+	equal(t, "/users/{id}?tab={tab|[a-z]+}", `/ path:"users" / { slot:"id" } ? key:"tab" = { slot:"tab" | regexp:"[a-z]+" }`)
+	equal(t, "/search?q=a b", `/ path:"search" ? key:"q" = value:"a" error:"unexpected character ' ' in query" value:"b"`)
+	equal(t, "/search?=a", `/ path:"search" error:"unexpected character '?=' in path" path:"a"`)
+}
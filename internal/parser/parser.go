@@ -10,6 +10,7 @@ import (
 	"github.com/matthewmueller/enroute/ast"
 	"github.com/matthewmueller/enroute/internal/lexer"
 	"github.com/matthewmueller/enroute/internal/token"
+	"golang.org/x/text/unicode/norm"
 	"slices"
 )
 
@@ -17,17 +18,66 @@ func New(l *lexer.Lexer) *Parser {
 	return &Parser{l: l}
 }
 
-func Parse(input string) (*ast.Route, error) {
-	p := New(lexer.New(input))
+// Option configures the parser
+type Option func(p *Parser)
+
+// AllowUppercase allows uppercase letters in paths
+func AllowUppercase() Option {
+	return func(p *Parser) {
+		p.upper = true
+	}
+}
+
+// CaseSensitive allows uppercase letters in paths and marks paths as case
+// sensitive when matching
+func CaseSensitive() Option {
+	return func(p *Parser) {
+		p.upper = true
+		p.caseSensitive = true
+	}
+}
+
+func Parse(input string, options ...Option) (*ast.Route, error) {
+	p := new(Parser)
+	for _, option := range options {
+		option(p)
+	}
+	var lexerOptions []lexer.Option
+	if p.upper {
+		lexerOptions = append(lexerOptions, lexer.AllowUppercase())
+	}
+	// Normalize the route, so it matches paths in any unicode form
+	p.l = lexer.New(norm.NFC.String(input), lexerOptions...)
 	return p.Parse()
 }
 
 type Parser struct {
-	l *lexer.Lexer
+	l             *lexer.Lexer
+	upper         bool // Allow uppercase letters in paths
+	caseSensitive bool // Match paths case sensitively
 }
 
 func (p *Parser) Parse() (*ast.Route, error) {
-	return p.parseRoute()
+	route, err := p.parseRoute()
+	if err != nil {
+		return nil, &Error{p.l.Token.Start, err}
+	}
+	return route, nil
+}
+
+// Error in a route with the byte offset of the token where it occurred. The
+// offset is within the normalized route.
+type Error struct {
+	Offset int
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
 }
 
 func (p *Parser) tokenText() string {
@@ -40,7 +90,24 @@ func (p *Parser) tokenType() token.Type {
 
 func (p *Parser) parseRoute() (*ast.Route, error) {
 	route := new(ast.Route)
+	if p.isHost() {
+		host, err := p.parseHost()
+		if err != nil {
+			return nil, err
+		}
+		route.Host = host
+		// Routes with only a host match the root path
+		route.Sections = append(route.Sections, &ast.Slash{Value: "/"})
+	}
 	for p.next() {
+		if p.tokenType() == token.Question {
+			query, err := p.parseQuery()
+			if err != nil {
+				return nil, err
+			}
+			route.Query = query
+			break
+		}
 		section, err := p.parseSection()
 		if err != nil {
 			return nil, err
@@ -50,6 +117,155 @@ func (p *Parser) parseRoute() (*ast.Route, error) {
 	return route, nil
 }
 
+// parseQuery parses the query parameters after the question mark
+func (p *Parser) parseQuery() (query []*ast.Query, err error) {
+	seen := map[string]bool{}
+	for {
+		if err := p.expect(token.Key); err != nil {
+			return nil, err
+		}
+		key := p.tokenText()
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate query parameter %q", key)
+		}
+		seen[key] = true
+		param := &ast.Query{Key: key}
+		if p.accept(token.Equal) {
+			switch tok := p.l.Peak(1); tok.Type {
+			case token.Value:
+				p.next()
+				param.Value = &ast.Path{Value: p.tokenText(), CaseSensitive: true}
+			case token.OpenCurly:
+				p.next()
+				slot, err := p.parseQuerySlot()
+				if err != nil {
+					return nil, err
+				}
+				param.Value = slot
+			case token.Error:
+				p.next()
+				return nil, errors.New(tok.Text)
+			default:
+				return nil, fmt.Errorf("missing value for query parameter %q", key)
+			}
+		}
+		query = append(query, param)
+		if !p.accept(token.Amp) {
+			break
+		}
+	}
+	if err := p.expect(token.End); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
+
+// parseQuerySlot parses a slot in the query. Query slots take up the whole
+// value, so they're only delimited by the next parameter.
+func (p *Parser) parseQuerySlot() (ast.Slot, error) {
+	if err := p.expect(token.Slot); err != nil {
+		return nil, err
+	}
+	key := p.tokenText()
+	delimiters := map[rune]bool{'&': true}
+	var slot ast.Slot
+	switch {
+	case p.accept(token.Question):
+		slot = &ast.OptionalSlot{Key: key, Delimiters: delimiters}
+	case p.accept(token.Star):
+		return nil, fmt.Errorf("wildcard slots aren't supported in queries")
+	case p.accept(token.Pipe):
+		regexpSlot, err := p.parseRegexpSlot(key)
+		if err != nil {
+			return nil, err
+		}
+		regexpSlot.Delimiters = delimiters
+		return regexpSlot, nil
+	case p.accept(token.Colon):
+		typedSlot, err := p.parseTypedSlot(key)
+		if err != nil {
+			return nil, err
+		}
+		typedSlot.Delimiters = delimiters
+		return typedSlot, nil
+	default:
+		slot = &ast.RequiredSlot{Key: key, Delimiters: delimiters}
+	}
+	if err := p.expect(token.CloseCurly); err != nil {
+		return nil, err
+	}
+	return slot, nil
+}
+
+// isHost is true when the route starts with a host
+func (p *Parser) isHost() bool {
+	switch p.l.Peak(1).Type {
+	case token.Host, token.Dot, token.OpenCurly:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseHost parses the host up to and including the slash that starts the
+// path. Hosts are matched regardless of their casing.
+func (p *Parser) parseHost() (host ast.Sections, err error) {
+	for p.next() {
+		switch p.tokenType() {
+		case token.Error:
+			return nil, errors.New(p.tokenText())
+		case token.Slash:
+			return host, nil
+		case token.Host, token.Dot:
+			// Join the labels and dots into a single path
+			if len(host) > 0 {
+				if path, ok := host[len(host)-1].(*ast.Path); ok {
+					path.Value += p.tokenText()
+					continue
+				}
+			}
+			host = append(host, &ast.Path{Value: p.tokenText()})
+		case token.OpenCurly:
+			slot, err := p.parseHostSlot()
+			if err != nil {
+				return nil, err
+			}
+			host = append(host, slot)
+		default:
+			return nil, fmt.Errorf("unexpected token %s in host", p.tokenType())
+		}
+	}
+	return host, nil
+}
+
+// parseHostSlot parses a slot in the host. Host slots are delimited by dots
+// instead of slashes.
+func (p *Parser) parseHostSlot() (ast.Slot, error) {
+	switch p.l.Peak(2).Type {
+	case token.Question:
+		return nil, fmt.Errorf("optional slots aren't supported in hosts")
+	case token.Star:
+		return nil, fmt.Errorf("wildcard slots aren't supported in hosts")
+	}
+	slot, err := p.parseSlot()
+	if err != nil {
+		return nil, err
+	}
+	delimiters := map[rune]bool{'.': true}
+	if tok := p.l.Peak(1); tok.Type == token.Host {
+		delimiters[firstRune(tok.Text)] = true
+	}
+	switch s := slot.(type) {
+	case *ast.RequiredSlot:
+		s.Delimiters = delimiters
+	case *ast.RegexpSlot:
+		s.Delimiters = delimiters
+	case *ast.TypedSlot:
+		s.Delimiters = delimiters
+	}
+	return slot, nil
+}
+
 func (p *Parser) parseSection() (ast.Section, error) {
 	switch p.tokenType() {
 	case token.Error:
@@ -71,7 +287,8 @@ func (p *Parser) parseSlash() (*ast.Slash, error) {
 
 func (p *Parser) parsePath() (*ast.Path, error) {
 	return &ast.Path{
-		Value: p.tokenText(),
+		Value:         p.tokenText(),
+		CaseSensitive: p.caseSensitive,
 	}, nil
 }
 
@@ -87,6 +304,8 @@ func (p *Parser) parseSlot() (ast.Slot, error) {
 		return p.parseWildcardSlot(key)
 	case p.accept(token.Pipe):
 		return p.parseRegexpSlot(key)
+	case p.accept(token.Colon):
+		return p.parseTypedSlot(key)
 	default:
 		return p.parseRequiredSlot(key)
 	}
@@ -95,19 +314,19 @@ func (p *Parser) parseSlot() (ast.Slot, error) {
 func (p *Parser) parseOptionalSlot(key string) (*ast.OptionalSlot, error) {
 	node := &ast.OptionalSlot{
 		Key: key,
-		Delimiters: map[byte]bool{
+		Delimiters: map[rune]bool{
 			'/': true,
 		},
 	}
 	if err := p.expect(token.CloseCurly); err != nil {
 		return nil, err
 	}
-	if err := p.expect(token.End); err != nil {
+	if !p.check(token.End) && !p.check(token.Question) {
 		return nil, fmt.Errorf("optional slots must be at the end of the path")
 	}
 	switch tok := p.l.Peak(1); tok.Type {
 	case token.Path:
-		node.Delimiters[tok.Text[0]] = true
+		node.Delimiters[firstRune(tok.Text)] = true
 	case token.OpenCurly:
 		return nil, &ErrSlotAfterSlot{key}
 	}
@@ -117,19 +336,19 @@ func (p *Parser) parseOptionalSlot(key string) (*ast.OptionalSlot, error) {
 func (p *Parser) parseWildcardSlot(key string) (*ast.WildcardSlot, error) {
 	node := &ast.WildcardSlot{
 		Key: key,
-		Delimiters: map[byte]bool{
+		Delimiters: map[rune]bool{
 			'/': true,
 		},
 	}
 	if err := p.expect(token.CloseCurly); err != nil {
 		return nil, err
 	}
-	if err := p.expect(token.End); err != nil {
+	if !p.check(token.End) && !p.check(token.Question) {
 		return nil, fmt.Errorf("wildcard slots must be at the end of the path")
 	}
 	switch tok := p.l.Peak(1); tok.Type {
 	case token.Path:
-		node.Delimiters[tok.Text[0]] = true
+		node.Delimiters[firstRune(tok.Text)] = true
 	case token.OpenCurly:
 		return nil, &ErrSlotAfterSlot{key}
 	}
@@ -139,7 +358,7 @@ func (p *Parser) parseWildcardSlot(key string) (*ast.WildcardSlot, error) {
 func (p *Parser) parseRegexpSlot(key string) (*ast.RegexpSlot, error) {
 	node := &ast.RegexpSlot{
 		Key: key,
-		Delimiters: map[byte]bool{
+		Delimiters: map[rune]bool{
 			'/': true,
 		},
 	}
@@ -175,7 +394,36 @@ func (p *Parser) parseRegexpSlot(key string) (*ast.RegexpSlot, error) {
 	}
 	switch tok := p.l.Peak(1); tok.Type {
 	case token.Path:
-		node.Delimiters[tok.Text[0]] = true
+		node.Delimiters[firstRune(tok.Text)] = true
+	case token.OpenCurly:
+		return nil, &ErrSlotAfterSlot{key}
+	}
+	return node, nil
+}
+
+func (p *Parser) parseTypedSlot(key string) (*ast.TypedSlot, error) {
+	node := &ast.TypedSlot{
+		Key: key,
+		Delimiters: map[rune]bool{
+			'/': true,
+		},
+	}
+	if err := p.expect(token.TypeName); err != nil {
+		return nil, err
+	}
+	name := p.tokenText()
+	t, ok := ast.LookupType(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown type %q in slot %q", name, key)
+	}
+	node.TypeName = name
+	node.Type = t
+	if err := p.expect(token.CloseCurly); err != nil {
+		return nil, err
+	}
+	switch tok := p.l.Peak(1); tok.Type {
+	case token.Path:
+		node.Delimiters[firstRune(tok.Text)] = true
 	case token.OpenCurly:
 		return nil, &ErrSlotAfterSlot{key}
 	}
@@ -185,7 +433,7 @@ func (p *Parser) parseRegexpSlot(key string) (*ast.RegexpSlot, error) {
 func (p *Parser) parseRequiredSlot(key string) (*ast.RequiredSlot, error) {
 	node := &ast.RequiredSlot{
 		Key: key,
-		Delimiters: map[byte]bool{
+		Delimiters: map[rune]bool{
 			'/': true,
 		},
 	}
@@ -194,7 +442,7 @@ func (p *Parser) parseRequiredSlot(key string) (*ast.RequiredSlot, error) {
 	}
 	switch tok := p.l.Peak(1); tok.Type {
 	case token.Path:
-		node.Delimiters[tok.Text[0]] = true
+		node.Delimiters[firstRune(tok.Text)] = true
 	case token.OpenCurly:
 		return nil, &ErrSlotAfterSlot{key}
 	}
@@ -230,11 +478,17 @@ func (p *Parser) accept(tokens ...token.Type) bool {
 func (p *Parser) expect(tokens ...token.Type) error {
 	for i, tok := range tokens {
 		peaked := p.l.Peak(i + 1)
+		if peaked.Type == tok {
+			continue
+		}
+		// Advance to the unexpected token to report where it is
+		for range i + 1 {
+			p.l.Next()
+		}
 		if peaked.Type == token.Error {
 			return fmt.Errorf(peaked.Text)
-		} else if peaked.Type != tok {
-			return fmt.Errorf("expected %s, got %s", tok, peaked.Type)
 		}
+		return fmt.Errorf("expected %s, got %s", tok, peaked.Type)
 	}
 	for i := 0; i < len(tokens); i++ {
 		p.l.Next()
@@ -323,3 +577,9 @@ func hasSlash(re *syntax.Regexp) bool {
 
 	return false
 }
+
+// firstRune returns the first rune of the text
+func firstRune(text string) rune {
+	r, _ := utf8.DecodeRuneInString(text)
+	return r
+}
@@ -1,9 +1,12 @@
 package parser_test
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/matryer/is"
 	"github.com/matthewmueller/diff"
+	"github.com/matthewmueller/enroute/ast"
 	"github.com/matthewmueller/enroute/internal/parser"
 )
 
@@ -110,3 +113,128 @@ func TestAll(t *testing.T) {
 	equal(t, "/{first?}/{last}", `optional slots must be at the end of the path`)
 	equal(t, "/{first*}/{last}", `wildcard slots must be at the end of the path`)
 }
+
+func TestTypedSlot(t *testing.T) {
+	equal(t, "/{id:int}", `/{id:int}`)
+	equal(t, "/users/{id:uuid}/edit", `/users/{id:uuid}/edit`)
+	equal(t, "/archive/{day:date}.{format}", `/archive/{day:date}.{format}`)
+	equal(t, "/{id:float}", `unknown type "float" in slot "id"`)
+	equal(t, "/{id:}", `missing type in slot`)
+	equal(t, "/{id:int", `unclosed slot`)
+	equal(t, "/{id:int?}", `expected '}' but got '?'`)
+	equal(t, "/{id:int}{name}", `slot "id" can't have another slot after`)
+}
+
+func TestCaseSensitive(t *testing.T) {
+	is := is.New(t)
+	route, err := parser.Parse("/API/Docs", parser.CaseSensitive())
+	is.NoErr(err)
+	is.Equal(route.String(), "/API/Docs")
+	path, ok := route.Sections[1].(*ast.Path)
+	is.True(ok)
+	is.True(path.CaseSensitive)
+	route, err = parser.Parse("/API/Docs", parser.AllowUppercase())
+	is.NoErr(err)
+	is.Equal(route.String(), "/API/Docs")
+	path, ok = route.Sections[1].(*ast.Path)
+	is.True(ok)
+	is.True(!path.CaseSensitive)
+	_, err = parser.Parse("/API/Docs")
+	is.True(err != nil)
+	is.Equal(err.Error(), "unexpected character 'API' in path")
+}
+
+func TestUnicode(t *testing.T) {
+	is := is.New(t)
+	equal(t, "/日本/{id}", "/日本/{id}")
+	// Decomposed routes are normalized to NFC
+	route, err := parser.Parse("/café/{name}")
+	is.NoErr(err)
+	is.Equal(route.String(), "/café/{name}")
+	// Delimiters are runes
+	route, err = parser.Parse("/{a}é{b}")
+	is.NoErr(err)
+	slot, ok := route.Sections[1].(*ast.RequiredSlot)
+	is.True(ok)
+	is.Equal(slot.Delimiters, map[rune]bool{'/': true, 'é': true})
+}
+
+func TestHost(t *testing.T) {
+	equal(t, "example.com", "example.com/")
+	equal(t, "example.com/", "example.com/")
+	equal(t, "{tenant}.example.com/dashboard", "{tenant}.example.com/dashboard")
+	equal(t, "{tenant|[a-z]+}.example.com/{id}", "{tenant|^[a-z]+$}.example.com/{id}")
+	equal(t, "{region}-{id:int}.example.com/{path*}", "{region}-{id:int}.example.com/{path*}")
+	equal(t, "{tenant?}.example.com/", "optional slots aren't supported in hosts")
+	equal(t, "{tenant*}.example.com/", "wildcard slots aren't supported in hosts")
+	equal(t, "{a}{b}.example.com/", `slot "a" can't have another slot after`)
+	equal(t, "Example.com/", "unexpected character 'E' in host")
+}
+
+func TestHostDelimiters(t *testing.T) {
+	is := is.New(t)
+	route, err := parser.Parse("{region}-{tenant}.example.com/{id}")
+	is.NoErr(err)
+	is.Equal(len(route.Host), 4)
+	region, ok := route.Host[0].(*ast.RequiredSlot)
+	is.True(ok)
+	is.Equal(region.Delimiters, map[rune]bool{'.': true, '-': true})
+	tenant, ok := route.Host[2].(*ast.RequiredSlot)
+	is.True(ok)
+	is.Equal(tenant.Delimiters, map[rune]bool{'.': true})
+	id, ok := route.Sections[1].(*ast.RequiredSlot)
+	is.True(ok)
+	is.Equal(id.Delimiters, map[rune]bool{'/': true})
+}
+
+func TestQuery(t *testing.T) {
+	equal(t, "/search?q={query}&page={page?}", "/search?q={query}&page={page?}")
+	equal(t, "/search?type=post&q={query}", "/search?type=post&q={query}")
+	equal(t, "/search?debug", "/search?debug")
+	equal(t, "/users/{id}?tab={tab|[a-z]+}", "/users/{id}?tab={tab|^[a-z]+$}")
+	equal(t, "/users/{id}?limit={limit:int}", "/users/{id}?limit={limit:int}")
+	equal(t, "/users/{id?}?tab={tab}", "/users/{id?}?tab={tab}")
+	equal(t, "/files/{path*}?raw", "/files/{path*}?raw")
+	equal(t, "/search?q={query*}", "wildcard slots aren't supported in queries")
+	equal(t, "/search?q=", `missing value for query parameter "q"`)
+	equal(t, "/search?q&q", `duplicate query parameter "q"`)
+}
+
+func TestQueryRequired(t *testing.T) {
+	is := is.New(t)
+	route, err := parser.Parse("/search?q={query}&page={page?}&type=post&debug")
+	is.NoErr(err)
+	is.Equal(len(route.Query), 4)
+	is.Equal(route.Query[0].Required(), true)
+	is.Equal(route.Query[1].Required(), false)
+	is.Equal(route.Query[2].Required(), true)
+	is.Equal(route.Query[3].Required(), true)
+	is.True(route.Query[0].Match("hello", true))
+	is.True(!route.Query[0].Match("", true))
+	is.True(route.Query[1].Match("", false))
+	is.True(route.Query[2].Match("post", true))
+	is.True(!route.Query[2].Match("user", true))
+	is.True(route.Query[3].Match("", true))
+	is.True(!route.Query[3].Match("", false))
+}
+
+func TestErrorOffset(t *testing.T) {
+	is := is.New(t)
+	tests := []struct {
+		route  string
+		offset int
+	}{
+		{"/Users", 1},
+		{"/users/{id", 10},
+		{"/users/{id:}", 11},
+		{"/search?q={query", 16},
+		{"/search?q=", 9},
+	}
+	for _, test := range tests {
+		_, err := parser.Parse(test.route)
+		is.True(err != nil)
+		var parseErr *parser.Error
+		is.True(errors.As(err, &parseErr))
+		is.Equal(parseErr.Offset, test.offset) // offset of the error in the route
+	}
+}
@@ -11,7 +11,7 @@ type Token struct {
 	Type  Type
 	Text  string
 	Start int
-	Line  int
+	Line  int // Line of the token in route files, starting at 1
 }
 
 func (t *Token) String() string {
@@ -29,6 +29,7 @@ const (
 	Error      Type = "error"
 	Regexp     Type = "regexp"
 	Path       Type = "path"
+	Host       Type = "host"
 	Slot       Type = "slot"
 	Slash      Type = "/"
 	OpenCurly  Type = "{"
@@ -36,4 +37,16 @@ const (
 	Question   Type = "?"
 	Star       Type = "*"
 	Pipe       Type = "|"
+	Colon      Type = ":"
+	TypeName   Type = "type"
+	Dot        Type = "."
+	Key        Type = "key"
+	Value      Type = "value"
+	Equal      Type = "="
+	Amp        Type = "&"
+
+	// Route files
+	Word    Type = "word"
+	Comment Type = "comment"
+	Newline Type = "newline"
 )
@@ -0,0 +1,58 @@
+package enroute
+
+import (
+	"slices"
+	"strings"
+)
+
+// MatchAll returns every route that matches the path, in the order Match tries
+// them, so the first match is the one Match returns. Each match links to the
+// next with Match.Next, so handlers can fall through to the next route without
+// matching again.
+func (t *Tree[V]) MatchAll(input string) []*Match[V] {
+	return t.matchAll(new(matcher), input)
+}
+
+// MatchAllMethod returns every route that matches the path and handles the
+// method, in the order MatchMethod tries them
+func (t *Tree[V]) MatchAllMethod(method string, input string) []*Match[V] {
+	return t.matchAll(&matcher{method: strings.ToUpper(method)}, input)
+}
+
+func (t *Tree[V]) matchAll(m *matcher, input string) (matches []*Match[V]) {
+	input, host, path := t.prepare(m, input)
+	// Routes of each matching host come before routes without a host
+	for _, h := range t.matchHost(host) {
+		hostSlots := createSlots(h.node.route, h.slotValues, DecodeEncodedSlash)
+		for _, match := range t.trees[h.node.Label].matchPaths(m, path) {
+			match.Slots = slices.Concat(hostSlots, match.Slots)
+			matches = append(matches, match)
+		}
+	}
+	matches = append(matches, t.matchPaths(m, path)...)
+	for i, match := range matches {
+		match.Path = input
+		if i+1 < len(matches) {
+			match.next = matches[i+1]
+		}
+	}
+	return matches
+}
+
+// matchPaths returns every route in the tree that matches the path
+func (t *Tree[V]) matchPaths(m *matcher, path string) (matches []*Match[V]) {
+	if t.root == nil || len(path) == 0 || path[0] != '/' {
+		return nil
+	}
+	t.root.matches(m, path, []slotValue{}, func(node *Node[V], slotValues []slotValue) bool {
+		matches = append(matches, t.newMatch(m, node, path, slotValues))
+		return true
+	})
+	return matches
+}
+
+// Next returns the match after this one from MatchAll or nil if it's the last
+// match. Matches from Match and MatchMethod don't have a next match.
+func (m *Match[V]) Next() *Match[V] {
+	return m.next
+}
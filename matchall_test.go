@@ -0,0 +1,108 @@
+package enroute_test
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/matthewmueller/enroute"
+)
+
+func matchRoutes[V any](matches []*enroute.Match[V]) (routes []string) {
+	for _, match := range matches {
+		routes = append(routes, match.String())
+	}
+	return routes
+}
+
+func TestMatchAll(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/users/new", "new"))
+	is.NoErr(tree.Insert("/users/{id:int}", "typed"))
+	is.NoErr(tree.Insert("/users/{id}", "required"))
+	is.NoErr(tree.Insert("/users/{id}.{format?}", "format"))
+	is.NoErr(tree.Insert("/{path*}", "wildcard"))
+	is.NoErr(tree.Insert("/posts", "posts"))
+	matches := tree.MatchAll("/users/new")
+	is.Equal(matchRoutes(matches), []string{
+		"/users/new",
+		"/users/{id} id=new",
+		"/{path*} path=users/new",
+	})
+	matches = tree.MatchAll("/users/10")
+	is.Equal(matchRoutes(matches), []string{
+		"/users/{id:int} id=10",
+		"/users/{id} id=10",
+		"/{path*} path=users/10",
+	})
+	// The first match is the one Match returns
+	match, err := tree.Match("/users/10")
+	is.NoErr(err)
+	is.Equal(match.Route, matches[0].Route)
+	is.Equal(match.Next(), nil)
+	is.Equal(len(tree.MatchAll("/comments")), 1)
+	is.Equal(len(enroute.New().MatchAll("/comments")), 0)
+}
+
+func TestMatchAllNext(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/{slug}", "page"))
+	is.NoErr(tree.Insert("/{path*}", "static"))
+	is.NoErr(tree.Insert("/about", "about"))
+	// Each handler can decline and fall through to the next match
+	var tried []string
+	for match := tree.MatchAll("/about")[0]; match != nil; match = match.Next() {
+		tried = append(tried, match.Value)
+		if match.Value == "page" {
+			break
+		}
+	}
+	is.Equal(tried, []string{"about", "page"})
+}
+
+func TestMatchAllMethod(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.InsertMethod("GET", "/users/{id}", "show"))
+	is.NoErr(tree.InsertMethod("POST", "/users/{id}", "update"))
+	is.NoErr(tree.InsertMethod("GET", "/users/new", "new"))
+	is.NoErr(tree.Insert("/{path*}", "static"))
+	matches := tree.MatchAllMethod("get", "/users/new")
+	is.Equal(len(matches), 3)
+	is.Equal(matches[0].Value, "new")
+	is.Equal(matches[1].Value, "show")
+	is.Equal(matches[2].Value, "static")
+	matches = tree.MatchAllMethod("POST", "/users/new")
+	is.Equal(len(matches), 2)
+	is.Equal(matches[0].Value, "update")
+	is.Equal(matches[1].Value, "static")
+}
+
+func TestMatchAllHostQuery(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("{tenant}.example.com/{page}", "tenant"))
+	is.NoErr(tree.Insert("/search", "search"))
+	is.NoErr(tree.Insert("/search?q={query}", "query"))
+	is.NoErr(tree.Insert("/{page}", "page"))
+	matches := tree.MatchAll("acme.example.com/search?q=hi")
+	is.Equal(matchRoutes(matches), []string{
+		"{tenant}.example.com/{page} tenant=acme&page=search",
+		"/search?q={query} query=hi",
+		"/search",
+		"/{page} page=search",
+	})
+	is.Equal(matches[0].Path, "acme.example.com/search")
+	// Routes of every matching host are included
+	is.NoErr(tree.Insert("api.example.com/users", "api"))
+	matches = tree.MatchAll("api.example.com/search")
+	is.Equal(matchRoutes(matches), []string{
+		"{tenant}.example.com/{page} tenant=api&page=search",
+		"/search",
+		"/{page} page=search",
+	})
+	concurrent := enroute.NewConcurrent[string]()
+	is.NoErr(concurrent.Insert("/{page}", "page"))
+	is.Equal(len(concurrent.MatchAll("/search")), 1)
+}
@@ -0,0 +1,138 @@
+package enroute
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Meta is metadata about a route for docs and policy checks. Meta is shared
+// between the tree and its matches, so it shouldn't be modified.
+type Meta struct {
+	Tags        []string
+	Middleware  []string // Middleware IDs in the order they run
+	Auth        []string // Auth requirements (e.g. "admin")
+	Description string
+	Owner       string
+	Deprecated  time.Time         // When the route was deprecated, zero if it isn't
+	Extra       map[string]string // Any other metadata
+}
+
+// HasTag is true if the route has the tag
+func (m *Meta) HasTag(tag string) bool {
+	return m != nil && slices.Contains(m.Tags, tag)
+}
+
+// merge returns a copy of the metadata with the other metadata on top. Tags,
+// middleware and auth requirements are added and the other fields are
+// replaced when they're set.
+func (m *Meta) merge(other *Meta) *Meta {
+	if other == nil {
+		return m
+	}
+	merged := new(Meta)
+	if m != nil {
+		*merged = *m
+		merged.Tags = slices.Clone(m.Tags)
+		merged.Middleware = slices.Clone(m.Middleware)
+		merged.Auth = slices.Clone(m.Auth)
+		merged.Extra = maps.Clone(m.Extra)
+	}
+	merged.Tags = union(merged.Tags, other.Tags)
+	merged.Middleware = union(merged.Middleware, other.Middleware)
+	merged.Auth = union(merged.Auth, other.Auth)
+	if other.Description != "" {
+		merged.Description = other.Description
+	}
+	if other.Owner != "" {
+		merged.Owner = other.Owner
+	}
+	if !other.Deprecated.IsZero() {
+		merged.Deprecated = other.Deprecated
+	}
+	if len(other.Extra) > 0 {
+		if merged.Extra == nil {
+			merged.Extra = map[string]string{}
+		}
+		maps.Copy(merged.Extra, other.Extra)
+	}
+	return merged
+}
+
+// union adds the values that aren't in the list yet
+func union(list, values []string) []string {
+	for _, value := range values {
+		if !slices.Contains(list, value) {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// mods describes the metadata for the tree's printer
+func (m *Meta) mods() (mods []string) {
+	if m == nil {
+		return nil
+	}
+	if len(m.Tags) > 0 {
+		mods = append(mods, "tags="+strings.Join(m.Tags, "|"))
+	}
+	if len(m.Middleware) > 0 {
+		mods = append(mods, "middleware="+strings.Join(m.Middleware, "|"))
+	}
+	if len(m.Auth) > 0 {
+		mods = append(mods, "auth="+strings.Join(m.Auth, "|"))
+	}
+	if m.Owner != "" {
+		mods = append(mods, "owner="+m.Owner)
+	}
+	if !m.Deprecated.IsZero() {
+		mods = append(mods, "deprecated="+m.Deprecated.Format(time.DateOnly))
+	}
+	if m.Description != "" {
+		mods = append(mods, fmt.Sprintf("description=%q", m.Description))
+	}
+	keys := make([]string, 0, len(m.Extra))
+	for key := range m.Extra {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		mods = append(mods, key+"="+m.Extra[key])
+	}
+	return mods
+}
+
+// Annotate the route with metadata. Annotating a route again adds to its tags,
+// middleware and auth requirements and replaces the other fields that are set.
+func (t *Tree[V]) Annotate(route string, meta *Meta) error {
+	r, err := t.parse(route)
+	if err != nil {
+		return err
+	}
+	label := r.String()
+	found := false
+	t.Each(func(n *Node[V]) bool {
+		if n.Label == label {
+			n.Meta = n.Meta.merge(meta)
+			found = true
+		}
+		// Shadowed routes are shared between clones, so they're replaced
+		for i, shadowed := range n.shadowed {
+			if shadowed.Label == label {
+				annotated := *shadowed
+				annotated.Meta = shadowed.Meta.merge(meta)
+				n.shadowed[i] = &annotated
+				found = true
+			}
+		}
+		return true
+	})
+	if !found {
+		return fmt.Errorf("%w for %s", ErrNoMatch, route)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package enroute_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/matthewmueller/diff"
+	"github.com/matthewmueller/enroute"
+)
+
+func TestAnnotate(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/users/{id?}", "users"))
+	is.NoErr(tree.Insert("/posts", "posts"))
+	is.NoErr(tree.Annotate("/users/{id?}", &enroute.Meta{
+		Tags:        []string{"users"},
+		Description: "Show users",
+		Owner:       "accounts",
+	}))
+	is.NoErr(tree.Annotate("/users/{id?}", &enroute.Meta{
+		Tags:       []string{"users", "deprecated"},
+		Deprecated: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		Extra:      map[string]string{"sunset": "2025-01-01"},
+	}))
+	diff.TestString(t, `
+/
+•users [from=/users/{id?}, tags=users|deprecated, owner=accounts, deprecated=2024-03-01, description="Show users", sunset=2025-01-01]
+••••••/{id} [from=/users/{id?}, tags=users|deprecated, owner=accounts, deprecated=2024-03-01, description="Show users", sunset=2025-01-01]
+•posts [from=/posts]
+`[1:], tree.String())
+	// Metadata is returned in matches of every expansion
+	match, err := tree.Match("/users/10")
+	is.NoErr(err)
+	is.Equal(match.Meta.Owner, "accounts")
+	is.True(match.Meta.HasTag("deprecated"))
+	match, err = tree.Match("/users")
+	is.NoErr(err)
+	is.Equal(match.Meta.Description, "Show users")
+	match, err = tree.Match("/posts")
+	is.NoErr(err)
+	is.Equal(match.Meta, nil)
+	is.True(!match.Meta.HasTag("users"))
+	err = tree.Annotate("/comments", &enroute.Meta{Owner: "accounts"})
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+}
+
+func TestAnnotatePolicy(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	admin := tree.Group("/admin").Tag("auth")
+	is.NoErr(admin.Insert("/users", "users"))
+	is.NoErr(admin.InsertMethod("DELETE", "/users/{id}", "delete"))
+	is.NoErr(tree.Insert("/admin/debug", "debug"))
+	is.NoErr(tree.Annotate("/admin/users", &enroute.Meta{Tags: []string{"users"}}))
+	// Every /admin route must have an auth tag
+	var missing []string
+	tree.Each(func(n *enroute.Node[string]) bool {
+		if n.Label != "" && !n.Meta.HasTag("auth") {
+			missing = append(missing, n.Label)
+		}
+		return true
+	})
+	is.Equal(missing, []string{"/admin/debug"})
+	node, err := tree.Find("/admin/users")
+	is.NoErr(err)
+	is.Equal(node.Meta.Tags, []string{"auth", "users"})
+}
+
+func TestAnnotateClone(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/users", "users"))
+	clone := tree.Clone()
+	is.NoErr(clone.Annotate("/users", &enroute.Meta{Owner: "accounts"}))
+	match, err := tree.Match("/users")
+	is.NoErr(err)
+	is.Equal(match.Meta, nil)
+	match, err = clone.Match("/users")
+	is.NoErr(err)
+	is.Equal(match.Meta.Owner, "accounts")
+}
+
+func TestAnnotateMount(t *testing.T) {
+	is := is.New(t)
+	sub := enroute.New()
+	is.NoErr(sub.Insert("/{id}", "show"))
+	is.NoErr(sub.Annotate("/{id}", &enroute.Meta{Owner: "accounts"}))
+	tree := enroute.NewConcurrent[string]()
+	is.NoErr(tree.Mount("/users", sub))
+	is.NoErr(tree.Annotate("/users/{id}", &enroute.Meta{Tags: []string{"users"}}))
+	match, err := tree.Match("/users/10")
+	is.NoErr(err)
+	is.Equal(match.Meta.Owner, "accounts")
+	is.Equal(match.Meta.Tags, []string{"users"})
+}
@@ -0,0 +1,106 @@
+package enroute
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/matthewmueller/enroute/ast"
+)
+
+// Mount the sub tree's routes under the prefix (e.g. /orgs/{org}). Slots in the
+// prefix come before the sub tree's slots in matches. Named routes are mounted
+// under the same name. If any of the routes conflict, the tree is left
+// unchanged.
+func (t *Tree[V]) Mount(prefix string, sub *Tree[V]) error {
+	p, err := t.parse(prefix)
+	if err != nil {
+		return err
+	}
+	next := t.Clone()
+	for route := range sub.Routes() {
+		mounted, err := t.mountRoute(p, sub, route.Route)
+		if err != nil {
+			return err
+		}
+		template := &Node[V]{
+			Value:   route.Value,
+			Meta:    route.Meta,
+			methods: route.Methods,
+			group:   route.Group,
+		}
+		if err := next.insertRoute(mounted, template); err != nil {
+			return err
+		}
+	}
+	for name, r := range sub.names {
+		if _, ok := next.names[name]; ok {
+			return fmt.Errorf("%w name already exists %q", ErrDuplicate, name)
+		}
+		route, err := t.mountRoute(p, sub, r.String())
+		if err != nil {
+			return err
+		}
+		if next.names == nil {
+			next.names = map[string]*ast.Route{}
+		}
+		if next.names[name], err = next.parse(route); err != nil {
+			return err
+		}
+	}
+	*t = *next
+	return nil
+}
+
+// mountRoute joins the prefix and the sub tree's route
+func (t *Tree[V]) mountRoute(prefix *ast.Route, sub *Tree[V], label string) (string, error) {
+	r, err := sub.parse(label)
+	if err != nil {
+		return "", err
+	}
+	return joinRoute(prefix, r, label)
+}
+
+// joinRoute joins the prefix and the route into a single route
+func joinRoute(prefix, r *ast.Route, label string) (string, error) {
+	if len(prefix.Query) > 0 {
+		return "", fmt.Errorf("enroute: prefix %q can't have a query", prefix)
+	}
+	if len(prefix.Host) > 0 && len(r.Host) > 0 {
+		return "", fmt.Errorf("enroute: %q with a host can't go under %q", label, prefix)
+	}
+	// Slots in the prefix would be ambiguous in matches
+	keys := map[string]bool{}
+	for _, key := range slotKeys(prefix) {
+		keys[key] = true
+	}
+	for _, key := range slotKeys(r) {
+		if keys[key] {
+			return "", fmt.Errorf("enroute: slot %q in %q is already in the prefix %q", key, label, prefix)
+		}
+	}
+	host := r.Host.String()
+	path := strings.TrimPrefix(r.String(), host)
+	base := strings.TrimRight(prefix.Sections.String(), "/")
+	// Mounting / under the prefix matches the prefix itself
+	if base != "" && (path == "/" || strings.HasPrefix(path, "/?")) {
+		path = path[1:]
+	}
+	return prefix.Host.String() + host + base + path, nil
+}
+
+// slotKeys returns the keys of the route's slots
+func slotKeys(r *ast.Route) (keys []string) {
+	for _, sections := range []ast.Sections{r.Host, r.Sections} {
+		for _, section := range sections {
+			if slot, ok := section.(ast.Slot); ok {
+				keys = append(keys, slot.Slot())
+			}
+		}
+	}
+	for _, q := range r.Query {
+		if slot, ok := q.Value.(ast.Slot); ok {
+			keys = append(keys, slot.Slot())
+		}
+	}
+	return keys
+}
@@ -0,0 +1,109 @@
+package enroute_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/matthewmueller/diff"
+	"github.com/matthewmueller/enroute"
+)
+
+func TestMount(t *testing.T) {
+	is := is.New(t)
+	repos := enroute.New()
+	is.NoErr(repos.Insert("/", "repos/index"))
+	is.NoErr(repos.Insert("/{repo}", "repos/show"))
+	is.NoErr(repos.Insert("/{repo}/issues/{number?}", "repos/issues"))
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/orgs", "orgs/index"))
+	is.NoErr(tree.Mount("/orgs/{org}/", repos))
+	diff.TestString(t, `
+/orgs [from=/orgs]
+•••••/{org} [from=/orgs/{org}]
+•••••••••••/{repo} [from=/orgs/{org}/{repo}]
+••••••••••••••••••/issues [from=/orgs/{org}/{repo}/issues/{number?}]
+•••••••••••••••••••••••••/{number} [from=/orgs/{org}/{repo}/issues/{number?}]
+`[1:], tree.String())
+	match, err := tree.Match("/orgs/acme")
+	is.NoErr(err)
+	is.Equal(match.Value, "repos/index")
+	// Prefix slots come before the sub tree's slots
+	match, err = tree.Match("/orgs/acme/enroute/issues/10")
+	is.NoErr(err)
+	is.Equal(match.String(), "/orgs/{org}/{repo}/issues/{number?} org=acme&repo=enroute&number=10")
+	is.Equal(match.Value, "repos/issues")
+	// Changes to the sub tree don't affect the mounted routes
+	is.NoErr(repos.Insert("/{repo}/pulls", "repos/pulls"))
+	_, err = tree.Match("/orgs/acme/enroute/pulls")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+}
+
+func TestMountMethods(t *testing.T) {
+	is := is.New(t)
+	users := enroute.New()
+	is.NoErr(users.InsertMethod("GET", "/{id}", "show"))
+	is.NoErr(users.InsertMethod("DELETE", "/{id}", "delete"))
+	is.NoErr(users.InsertNamed("user", "/{id}/edit", "edit"))
+	tree := enroute.New()
+	is.NoErr(tree.Mount("/users", users))
+	match, err := tree.MatchMethod("DELETE", "/users/10")
+	is.NoErr(err)
+	is.Equal(match.Value, "delete")
+	_, err = tree.MatchMethod("POST", "/users/10")
+	var notAllowed *enroute.ErrMethodNotAllowed
+	is.True(errors.As(err, &notAllowed))
+	is.Equal(notAllowed.Allowed, []string{"DELETE", "GET"})
+	url, err := tree.URL("user", &enroute.Slot{Key: "id", Value: "10"})
+	is.NoErr(err)
+	is.Equal(url, "/users/10/edit")
+}
+
+func TestMountHost(t *testing.T) {
+	is := is.New(t)
+	sub := enroute.New()
+	is.NoErr(sub.Insert("/{id}", "show"))
+	is.NoErr(sub.Insert("admin.example.com/", "admin"))
+	tree := enroute.New()
+	is.NoErr(tree.Mount("/users", sub))
+	match, err := tree.Match("/users/10")
+	is.NoErr(err)
+	is.Equal(match.Value, "show")
+	match, err = tree.Match("admin.example.com/users")
+	is.NoErr(err)
+	is.Equal(match.Route, "admin.example.com/users")
+	is.NoErr(tree.Mount("{tenant}.example.com/users", enroute.New()))
+	err = tree.Mount("{tenant}.example.com/accounts", sub)
+	is.Equal(err.Error(), `enroute: "admin.example.com/" with a host can't go under "{tenant}.example.com/accounts"`)
+}
+
+func TestMountConflict(t *testing.T) {
+	is := is.New(t)
+	sub := enroute.New()
+	is.NoErr(sub.Insert("/", "index"))
+	is.NoErr(sub.Insert("/{id}", "show"))
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/users/{name}", "existing"))
+	err := tree.Mount("/users", sub)
+	is.True(errors.Is(err, enroute.ErrDuplicate))
+	is.Equal(err.Error(), `route "/users/{id}" is ambiguous with "/users/{name}"`)
+	// The tree is left unchanged
+	_, err = tree.Match("/users")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	// Slots can't repeat the prefix's slots
+	err = tree.Mount("/posts/{id}", sub)
+	is.Equal(err.Error(), `enroute: slot "id" in "/{id}" is already in the prefix "/posts/{id}"`)
+	err = tree.Mount("/posts?q={query}", sub)
+	is.Equal(err.Error(), `enroute: prefix "/posts?q={query}" can't have a query`)
+}
+
+func TestMountConcurrent(t *testing.T) {
+	is := is.New(t)
+	sub := enroute.New()
+	is.NoErr(sub.Insert("/{id}", "show"))
+	tree := enroute.NewConcurrent[string]()
+	is.NoErr(tree.Mount("/users", sub))
+	match, err := tree.Match("/users/10")
+	is.NoErr(err)
+	is.Equal(match.String(), "/users/{id} id=10")
+}
@@ -0,0 +1,182 @@
+package enroute
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/matthewmueller/enroute/ast"
+)
+
+// Routes like /search?q={query} match the query too. Routes that share the
+// same path, but require different query parameters, are stored as variants
+// of the same node. While matching, the variants that require the most
+// parameters are tried first.
+
+// querySignature identifies the query parameters a route requires. Routes
+// with the same path and the same signature are ambiguous.
+func querySignature(r *ast.Route) string {
+	var required []string
+	for _, q := range r.Query {
+		if !q.Required() {
+			continue
+		}
+		if path, ok := q.Value.(*ast.Path); ok {
+			required = append(required, q.Key+"="+path.Value)
+			continue
+		}
+		required = append(required, q.Key)
+	}
+	sort.Strings(required)
+	return strings.Join(required, "&")
+}
+
+// requiredQuery counts the query parameters the route requires
+func requiredQuery(r *ast.Route) (count int) {
+	for _, q := range r.Query {
+		if q.Required() {
+			count++
+		}
+	}
+	return count
+}
+
+// insertVariant inserts a route with the same path as the node, but
+// different required query parameters
+func (n *Node[V]) insertVariant(entry *Node[V]) error {
+	signature := querySignature(entry.route)
+	for _, variant := range n.variants {
+		if querySignature(variant.route) != signature {
+			continue
+		}
+		// The same route is being inserted for another method
+		if variant.Label == entry.Label && variant.methods != nil && entry.methods != nil {
+			for method := range entry.methods {
+				if _, ok := variant.methods[method]; ok {
+					return fmt.Errorf("%w already exists %s %q", ErrDuplicate, method, variant.Label)
+				}
+			}
+			maps.Copy(variant.methods, entry.methods)
+			return nil
+		}
+		if variant.Label == entry.Label {
+			return fmt.Errorf("%w already exists %q", ErrDuplicate, entry.Label)
+		}
+		return fmt.Errorf("%w %q is ambiguous with %q", ErrDuplicate, entry.Label, variant.Label)
+	}
+	n.variants = append(n.variants, entry.with(n.sections))
+	return nil
+}
+
+// candidates returns the node and its variants in match order
+func (n *Node[V]) candidates() nodes[V] {
+	if len(n.variants) == 0 {
+		return nodes[V]{n}
+	}
+	candidates := append(nodes[V]{n}, n.variants...)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return requiredQuery(candidates[i].route) > requiredQuery(candidates[j].route)
+	})
+	return candidates
+}
+
+// variant returns the node or the variant with the label
+func (n *Node[V]) variant(label string) (*Node[V], bool) {
+	if n.Label == label {
+		return n, true
+	}
+	for _, variant := range n.variants {
+		if variant.Label == label {
+			return variant, true
+		}
+	}
+	return nil, false
+}
+
+// queryValue is a parameter in the input's query
+type queryValue struct {
+	raw   string
+	value string
+}
+
+// parseQuery parses the input's query. Only the first value of each key is
+// kept and parameters that aren't properly encoded are skipped.
+func parseQuery(query string) map[string]queryValue {
+	if query == "" {
+		return nil
+	}
+	values := map[string]queryValue{}
+	for _, param := range strings.Split(query, "&") {
+		rawKey, raw, _ := strings.Cut(param, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil || key == "" {
+			continue
+		}
+		value, err := url.QueryUnescape(raw)
+		if err != nil {
+			continue
+		}
+		if _, ok := values[key]; !ok {
+			values[key] = queryValue{raw, value}
+		}
+	}
+	return values
+}
+
+// acceptQuery is true if the query satisfies the route's query parameters
+func (m *matcher) acceptQuery(r *ast.Route) bool {
+	for _, q := range r.Query {
+		param, ok := m.query[q.Key]
+		if !q.Match(param.value, ok) {
+			return false
+		}
+	}
+	return true
+}
+
+// querySlots creates the slots for the route's query parameters. Optional
+// slots are left out when they're not in the query.
+func querySlots(r *ast.Route, query map[string]queryValue) (slots []*Slot) {
+	for _, q := range r.Query {
+		slot, ok := q.Value.(ast.Slot)
+		if !ok {
+			continue
+		}
+		param, ok := query[q.Key]
+		if !ok {
+			continue
+		}
+		var parsed any
+		if typed, ok := slot.(*ast.TypedSlot); ok {
+			parsed, _ = typed.Type.Parse(param.value)
+		}
+		slots = append(slots, &Slot{
+			Key:    slot.Slot(),
+			Value:  param.value,
+			Raw:    param.raw,
+			Parsed: parsed,
+		})
+	}
+	return slots
+}
+
+// cutQuery cuts the input around the first question mark that's not within a
+// slot like {id?}
+func cutQuery(input string) (path, query string) {
+	depth := 0
+	for i := 0; i < len(input); i++ {
+		switch input[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '?':
+			if depth == 0 {
+				return input[:i], input[i+1:]
+			}
+		}
+	}
+	return input, ""
+}
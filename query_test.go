@@ -0,0 +1,204 @@
+package enroute_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/matthewmueller/diff"
+	"github.com/matthewmueller/enroute"
+)
+
+func TestInsertQuery(t *testing.T) {
+	tree := enroute.New()
+	tree.MustInsert("/search", "search")
+	tree.MustInsert("/search?q={query}&page={page?}", "query")
+	tree.MustInsert("/search?type=post&q={query}", "posts")
+	tree.MustInsert("/users/{id}?tab={tab}", "tab")
+	diff.TestString(t, `
+/
+•search [from=/search]
+•search [from=/search?q={query}&page={page?}]
+•search [from=/search?type=post&q={query}]
+•users/{id} [from=/users/{id}?tab={tab}]
+`[1:], tree.String())
+}
+
+func TestMatchQuery(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/search", "search"))
+	is.NoErr(tree.Insert("/search?q={query}&page={page?}", "query"))
+	is.NoErr(tree.Insert("/search?type=post&q={query}", "posts"))
+	is.NoErr(tree.Insert("/search?debug", "debug"))
+	is.NoErr(tree.Insert("/users/{id}?tab={tab|[a-z]+}&limit={limit?}", "tab"))
+	is.NoErr(tree.Insert("/users/{id}?limit={limit:int}", "limit"))
+	match, err := tree.Match("/search?q=hello+world&page=2")
+	is.NoErr(err)
+	is.Equal(match.Route, "/search?q={query}&page={page?}")
+	is.Equal(match.Path, "/search")
+	is.Equal(match.String(), "/search?q={query}&page={page?} query=hello world&page=2")
+	is.Equal(match.Slots[0].Raw, "hello+world")
+	// Optional parameters are only bound when present
+	match, err = tree.Match("/search?q=hello")
+	is.NoErr(err)
+	is.Equal(match.String(), "/search?q={query}&page={page?} query=hello")
+	// Routes that require more parameters are tried first
+	match, err = tree.Match("/search?q=hello&type=post")
+	is.NoErr(err)
+	is.Equal(match.Value, "posts")
+	match, err = tree.Match("/search?q=hello&type=user")
+	is.NoErr(err)
+	is.Equal(match.Value, "query")
+	match, err = tree.Match("/search?debug&q=hello")
+	is.NoErr(err)
+	is.Equal(match.Value, "query")
+	match, err = tree.Match("/search?debug")
+	is.NoErr(err)
+	is.Equal(match.Value, "debug")
+	// Empty values don't satisfy required slots
+	match, err = tree.Match("/search?q=")
+	is.NoErr(err)
+	is.Equal(match.Value, "search")
+	match, err = tree.Match("/search/")
+	is.NoErr(err)
+	is.Equal(match.Value, "search")
+	// The first value wins
+	match, err = tree.Match("/search?q=a&q=b")
+	is.NoErr(err)
+	is.Equal(match.String(), "/search?q={query}&page={page?} query=a")
+	// Query slots come after path slots
+	match, err = tree.Match("/users/10?tab=posts&limit=5")
+	is.NoErr(err)
+	is.Equal(match.String(), "/users/{id}?tab={tab|^[a-z]+$}&limit={limit?} id=10&tab=posts&limit=5")
+	limit, err := match.Int("limit")
+	is.NoErr(err)
+	is.Equal(limit, 5)
+	match, err = tree.Match("/users/10?tab=123&limit=5")
+	is.NoErr(err)
+	is.Equal(match.Value, "limit")
+	match, err = tree.Match("/users/10?limit=five")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	is.Equal(match, nil)
+	match, err = tree.Match("/users/10")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	is.Equal(match, nil)
+}
+
+func TestQueryDuplicate(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/search?q={query}", "query"))
+	err := tree.Insert("/search?q={term}", "term")
+	is.True(errors.Is(err, enroute.ErrDuplicate))
+	is.Equal(err.Error(), `route "/search?q={term}" is ambiguous with "/search?q={query}"`)
+	// Optional parameters don't tell routes apart
+	is.NoErr(tree.Insert("/search?page={page?}", "page"))
+	err = tree.Insert("/search?limit={limit?}", "limit")
+	is.True(errors.Is(err, enroute.ErrDuplicate))
+	err = tree.Insert("/search?q={query}", "query")
+	is.True(errors.Is(err, enroute.ErrDuplicate))
+	is.Equal(err.Error(), `route already exists "/search?q={query}"`)
+	// Fixed values tell routes apart
+	is.NoErr(tree.Insert("/search?type=post", "posts"))
+	is.NoErr(tree.Insert("/search?type=user", "users"))
+	// Parameters can only appear once
+	err = tree.Insert("/search?q={a}&q={b}", "q")
+	is.Equal(err.Error(), `duplicate query parameter "q"`)
+}
+
+func TestQueryMethod(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.InsertMethod("GET", "/search?q={query}", "get"))
+	is.NoErr(tree.InsertMethod("POST", "/search?q={query}", "post"))
+	is.NoErr(tree.InsertMethod("GET", "/search", "all"))
+	err := tree.InsertMethod("GET", "/search?q={query}", "get")
+	is.True(errors.Is(err, enroute.ErrDuplicate))
+	match, err := tree.MatchMethod("POST", "/search?q=hello")
+	is.NoErr(err)
+	is.Equal(match.Value, "post")
+	match, err = tree.MatchMethod("GET", "/search?q=hello")
+	is.NoErr(err)
+	is.Equal(match.Value, "get")
+	match, err = tree.MatchMethod("GET", "/search")
+	is.NoErr(err)
+	is.Equal(match.Value, "all")
+	_, err = tree.MatchMethod("DELETE", "/search?q=hello")
+	var notAllowed *enroute.ErrMethodNotAllowed
+	is.True(errors.As(err, &notAllowed))
+	is.Equal(notAllowed.Path, "/search")
+	is.Equal(notAllowed.Allowed, []string{"GET", "POST"})
+}
+
+func TestDeleteQuery(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/search", "search"))
+	is.NoErr(tree.Insert("/search?q={query}", "query"))
+	is.NoErr(tree.Insert("/search?type={type}", "type"))
+	is.NoErr(tree.Delete("/search"))
+	diff.TestString(t, `
+/search [from=/search?q={query}]
+/search [from=/search?type={type}]
+`[1:], tree.String())
+	is.NoErr(tree.Delete("/search?type={type}"))
+	diff.TestString(t, `
+/search [from=/search?q={query}]
+`[1:], tree.String())
+	_, err := tree.Match("/search?type=post")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+	match, err := tree.Match("/search?q=hello")
+	is.NoErr(err)
+	is.Equal(match.Value, "query")
+	err = tree.Delete("/search?type={type}")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+}
+
+func TestFindQuery(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/search", "search"))
+	is.NoErr(tree.Insert("/search?q={query}", "query"))
+	node, err := tree.Find("/search?q={query}")
+	is.NoErr(err)
+	is.Equal(node.Value, "query")
+	node, err = tree.Find("/search")
+	is.NoErr(err)
+	is.Equal(node.Value, "search")
+	_, err = tree.Find("/search?type={type}")
+	is.True(errors.Is(err, enroute.ErrNoMatch))
+}
+
+func TestQueryClone(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/search", "search"))
+	is.NoErr(tree.Insert("/search?q={query}", "query"))
+	clone := tree.Clone()
+	is.NoErr(clone.Delete("/search?q={query}"))
+	match, err := tree.Match("/search?q=hello")
+	is.NoErr(err)
+	is.Equal(match.Value, "query")
+	match, err = clone.Match("/search?q=hello")
+	is.NoErr(err)
+	is.Equal(match.Value, "search")
+}
+
+func TestBuildQuery(t *testing.T) {
+	is := is.New(t)
+	path, err := enroute.Build("/search?q={query}&page={page?}&type=post", map[string]string{
+		"query": "hello world",
+	})
+	is.NoErr(err)
+	is.Equal(path, "/search?q=hello+world&type=post")
+	path, err = enroute.Build("/users/{id}/?tab={tab}&page={page?}", map[string]string{
+		"id":   "10",
+		"tab":  "posts",
+		"page": "2",
+	})
+	is.NoErr(err)
+	is.Equal(path, "/users/10?tab=posts&page=2")
+	_, err = enroute.Build("/search?q={query}", map[string]string{})
+	is.Equal(err.Error(), `missing value for slot "query"`)
+}
@@ -0,0 +1,122 @@
+package routefile
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/matthewmueller/enroute/ast"
+	"github.com/matthewmueller/enroute/internal/parser"
+)
+
+// Format the route file with its routes in canonical form. The methods, routes
+// and values are aligned into columns, while blank lines, comments and
+// directives are kept as they are.
+func (f *File) Format() (string, error) {
+	var errs []error
+	routes := make([]string, len(f.Lines))
+	methodWidth, routeWidth, valueWidth := 0, 0, 0
+	for i, line := range f.Lines {
+		if line.Kind != RouteLine {
+			continue
+		}
+		// Uppercase letters are kept for trees that are case sensitive
+		r, err := parser.Parse(line.Route, parser.AllowUppercase())
+		if err != nil {
+			errs = append(errs, &Error{line.RoutePos, err})
+			continue
+		}
+		routes[i] = canonical(r)
+		methodWidth = max(methodWidth, width(line.Method))
+		routeWidth = max(routeWidth, width(routes[i]))
+		valueWidth = max(valueWidth, width(line.Value))
+	}
+	if len(errs) > 0 {
+		return "", errors.Join(errs...)
+	}
+	s := new(strings.Builder)
+	for i, line := range f.Lines {
+		var columns []string
+		var widths []int
+		switch line.Kind {
+		case RouteLine:
+			columns = []string{routes[i], line.Value, line.Comment}
+			widths = []int{routeWidth, valueWidth}
+			if methodWidth > 0 {
+				columns = append([]string{line.Method}, columns...)
+				widths = append([]int{methodWidth}, widths...)
+			}
+		case IncludeLine:
+			columns = []string{strings.TrimSpace("include " + line.Include + " " + line.Prefix), line.Comment}
+		case PrefixLine:
+			columns = []string{strings.TrimSpace("prefix " + line.Prefix), line.Comment}
+		default:
+			columns = []string{line.Comment}
+		}
+		// Drop the empty columns at the end
+		for len(columns) > 0 && columns[len(columns)-1] == "" {
+			columns = columns[:len(columns)-1]
+		}
+		for j, column := range columns {
+			s.WriteString(column)
+			if j == len(columns)-1 {
+				break
+			}
+			padding := 1
+			if j < len(widths) {
+				padding = widths[j] - width(column) + 2
+			}
+			s.WriteString(strings.Repeat(" ", padding))
+		}
+		s.WriteString("\n")
+	}
+	return s.String(), nil
+}
+
+// width of the text in characters
+func width(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// canonical formats the route without a trailing slash, with the query
+// parameters sorted by key
+func canonical(r *ast.Route) string {
+	s := new(strings.Builder)
+	sections := r.Sections
+	if len(sections) > 1 {
+		if _, ok := sections[len(sections)-1].(*ast.Slash); ok {
+			sections = sections[:len(sections)-1]
+		}
+	}
+	for _, section := range slices.Concat(r.Host, sections) {
+		s.WriteString(formatSection(section))
+	}
+	query := slices.Clone(r.Query)
+	slices.SortStableFunc(query, func(a, b *ast.Query) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	for i, q := range query {
+		if i == 0 {
+			s.WriteString("?")
+		} else {
+			s.WriteString("&")
+		}
+		s.WriteString(q.Key)
+		if q.Value != nil {
+			s.WriteString("=")
+			s.WriteString(formatSection(q.Value))
+		}
+	}
+	return s.String()
+}
+
+// formatSection formats the section like it's written in a route. Regexp
+// patterns are stored with anchors that aren't part of the route.
+func formatSection(section ast.Section) string {
+	if s, ok := section.(*ast.RegexpSlot); ok {
+		pattern := s.Pattern.String()
+		return "{" + s.Key + "|" + pattern[1:len(pattern)-1] + "}"
+	}
+	return section.String()
+}
@@ -0,0 +1,56 @@
+package routefile_test
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/matthewmueller/diff"
+	"github.com/matthewmueller/enroute/routefile"
+)
+
+func TestFormat(t *testing.T) {
+	is := is.New(t)
+	file, err := routefile.Parse("routes", `
+# Users
+GET /users/{id}/  users.show # Show a user
+POST /users users.create
+   /posts/{id|[0-9]+}  posts.show
+
+include   admin.routes   /admin   # Admin
+prefix    /api
+/search?q={query}&page={page?} search
+/API/Docs docs
+/日本/{id} jp
+`)
+	is.NoErr(err)
+	formatted, err := file.Format()
+	is.NoErr(err)
+	diff.TestString(t, `
+# Users
+GET   /users/{id}                     users.show    # Show a user
+POST  /users                          users.create
+      /posts/{id|[0-9]+}              posts.show
+
+include admin.routes /admin # Admin
+prefix /api
+      /search?page={page?}&q={query}  search
+      /API/Docs                       docs
+      /日本/{id}                        jp
+`, formatted)
+	// Formatting is idempotent
+	file, err = routefile.Parse("routes", formatted)
+	is.NoErr(err)
+	again, err := file.Format()
+	is.NoErr(err)
+	is.Equal(again, formatted)
+}
+
+func TestFormatError(t *testing.T) {
+	is := is.New(t)
+	file, err := routefile.Parse("routes", "/users/{id users\nGET /posts/{id*}/edit posts\n")
+	is.NoErr(err)
+	_, err = file.Format()
+	diff.TestString(t, `
+routes:1:1: unclosed slot
+routes:2:5: wildcard slots must be at the end of the path`[1:], err.Error())
+}
@@ -0,0 +1,184 @@
+package routefile
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/matthewmueller/enroute/internal/token"
+)
+
+// lexer splits a route file into words, comments and newlines
+type lexer struct {
+	input string
+	next  int // Index of the next byte to lex
+	line  int // Line of the next byte
+}
+
+// Next token in the route file
+func (l *lexer) Next() token.Token {
+	// Skip the space between tokens
+	for l.next < len(l.input) && isSpace(l.input[l.next]) {
+		l.next++
+	}
+	start := l.next
+	if start == len(l.input) {
+		return token.Token{Type: token.End, Start: start, Line: l.line}
+	}
+	switch l.input[start] {
+	case '\n':
+		l.next++
+		l.line++
+		return token.Token{Type: token.Newline, Text: "\n", Start: start, Line: l.line - 1}
+	case '#':
+		// Comments run until the end of the line
+		for l.next < len(l.input) && l.input[l.next] != '\n' {
+			l.next++
+		}
+		text := strings.TrimRight(l.input[start:l.next], " \t\r")
+		return token.Token{Type: token.Comment, Text: text, Start: start, Line: l.line}
+	}
+	for l.next < len(l.input) && l.input[l.next] != '\n' && !isSpace(l.input[l.next]) {
+		l.next++
+	}
+	return token.Token{Type: token.Word, Text: l.input[start:l.next], Start: start, Line: l.line}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r'
+}
+
+// Parse the contents of a route file. The routes themselves are checked when
+// they're loaded into a tree, since that depends on the tree's options.
+func Parse(path string, source string) (*File, error) {
+	p := &lineParser{path: path, input: source, l: &lexer{input: source, line: 1}}
+	file := &File{Path: path}
+	for p.l.next < len(source) {
+		line, err := p.parseLine()
+		if err != nil {
+			return nil, err
+		}
+		file.Lines = append(file.Lines, line)
+	}
+	return file, nil
+}
+
+// lineParser parses the route file line by line
+type lineParser struct {
+	path  string
+	input string
+	l     *lexer
+}
+
+// parseLine parses the next line
+func (p *lineParser) parseLine() (*Line, error) {
+	line := &Line{Pos: Position{File: p.path, Line: p.l.line, Column: 1}}
+	var words []token.Token
+	for {
+		t := p.l.Next()
+		switch t.Type {
+		case token.Word:
+			words = append(words, t)
+		case token.Comment:
+			line.Comment = t.Text
+		default:
+			if err := p.parseWords(line, words); err != nil {
+				return nil, err
+			}
+			return line, nil
+		}
+	}
+}
+
+// parseWords fills in the line from its words
+func (p *lineParser) parseWords(line *Line, words []token.Token) error {
+	if len(words) == 0 {
+		return nil
+	}
+	line.Pos = p.pos(words[0])
+	switch words[0].Text {
+	case "include":
+		line.Kind = IncludeLine
+		if len(words) == 1 {
+			return p.errorf(p.end(words[0]), "missing file after include")
+		}
+		line.Include = words[1].Text
+		if len(words) > 2 {
+			if err := p.checkPrefix(words[2]); err != nil {
+				return err
+			}
+			line.Prefix = words[2].Text
+		}
+		return p.noMore(words, 3)
+	case "prefix":
+		line.Kind = PrefixLine
+		if len(words) > 1 {
+			if err := p.checkPrefix(words[1]); err != nil {
+				return err
+			}
+			line.Prefix = words[1].Text
+		}
+		return p.noMore(words, 2)
+	}
+	line.Kind = RouteLine
+	if isMethod(words[0].Text) {
+		line.Method = words[0].Text
+		if len(words) == 1 {
+			return p.errorf(p.end(words[0]), "missing route after %s", line.Method)
+		}
+		words = words[1:]
+	}
+	line.Route = words[0].Text
+	line.RoutePos = p.pos(words[0])
+	if len(words) > 1 {
+		line.Value = words[1].Text
+	}
+	return p.noMore(words, 2)
+}
+
+// checkPrefix checks that the prefix is a path
+func (p *lineParser) checkPrefix(word token.Token) error {
+	if !strings.HasPrefix(word.Text, "/") {
+		return p.errorf(p.pos(word), "prefix %q must start with a slash", word.Text)
+	}
+	return nil
+}
+
+// noMore returns an error if there are more than n words
+func (p *lineParser) noMore(words []token.Token, n int) error {
+	if len(words) > n {
+		return p.errorf(p.pos(words[n]), "unexpected %q", words[n].Text)
+	}
+	return nil
+}
+
+// isMethod is true for uppercase words like GET
+func isMethod(word string) bool {
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'A' || word[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// pos returns the position of the token
+func (p *lineParser) pos(t token.Token) Position {
+	return p.position(t.Line, t.Start)
+}
+
+// end returns the position right after the token
+func (p *lineParser) end(t token.Token) Position {
+	return p.position(t.Line, t.Start+len(t.Text))
+}
+
+// position of the offset on the line. Columns count characters, starting at 1.
+func (p *lineParser) position(line, offset int) Position {
+	start := strings.LastIndexByte(p.input[:offset], '\n') + 1
+	column := utf8.RuneCountInString(p.input[start:offset]) + 1
+	return Position{File: p.path, Line: line, Column: column}
+}
+
+func (p *lineParser) errorf(pos Position, format string, args ...any) error {
+	return &Error{Pos: pos, Err: fmt.Errorf(format, args...)}
+}
@@ -0,0 +1,256 @@
+// Package routefile reads route files into an enroute tree. Each line of a
+// route file has an optional method, a route and an optional value:
+//
+//	# Users
+//	GET  /users/{id}  users.show  # Show a user
+//	POST /users       users.create
+//	/about            about
+//
+// Route files can be split up with includes and prefixes:
+//
+//	include admin.routes /admin  # Include admin.routes under /admin
+//	prefix /api/v1               # Prefix the routes that follow
+//	GET /users                   api.users
+//	prefix                       # Stop prefixing routes
+package routefile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/matthewmueller/enroute"
+	"github.com/matthewmueller/enroute/internal/parser"
+	"golang.org/x/text/unicode/norm"
+)
+
+// File is a parsed route file
+type File struct {
+	Path  string
+	Lines []*Line
+}
+
+// Line in a route file
+type Line struct {
+	Pos      Position // Position of the line's first word
+	Kind     LineKind
+	Method   string   // Method of the route, empty for every method
+	Route    string   // Route as it's written in the file
+	RoutePos Position // Position of the route
+	Value    string
+	Include  string // File to include, relative to the route file
+	Prefix   string // Prefix of the following or included routes
+	Comment  string // Comment including the #, if any
+}
+
+// LineKind is the kind of line in a route file
+type LineKind uint8
+
+const (
+	// BlankLine is a line without a route or directive, but maybe a comment
+	BlankLine LineKind = iota
+	// RouteLine is a line with a route
+	RouteLine
+	// IncludeLine includes the routes of another file
+	IncludeLine
+	// PrefixLine prefixes the routes that follow. Prefix lines without a
+	// prefix stop prefixing routes.
+	PrefixLine
+)
+
+// Position in a route file
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// Error in a route file with its position
+type Error struct {
+	Pos Position
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Pos.String() + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Route read from a route file, with its includes and prefixes applied
+type Route struct {
+	Pos    Position // Position of the route in the file it was read from
+	Method string   // Method of the route, empty for every method
+	Route  string
+	Value  string
+
+	prefixed int // Bytes of the route that came from a prefix
+}
+
+// ReadFile reads the route file at the path, along with the files it includes
+func ReadFile(path string) ([]*Route, error) {
+	r := &reader{os.ReadFile, filepath.Join, filepath.Dir}
+	return r.read(path, "", nil)
+}
+
+// ReadFS reads the route file with the name from the filesystem, along with
+// the files it includes
+func ReadFS(fsys fs.FS, name string) ([]*Route, error) {
+	read := func(name string) ([]byte, error) {
+		return fs.ReadFile(fsys, name)
+	}
+	r := &reader{read, path.Join, path.Dir}
+	return r.read(name, "", nil)
+}
+
+// reader reads route files and their includes
+type reader struct {
+	readFile func(name string) ([]byte, error)
+	join     func(elem ...string) string
+	dir      func(name string) string
+}
+
+// read the routes of the file under the prefix. Including is the chain of
+// files that included this one.
+func (r *reader) read(name, prefix string, including []string) (routes []*Route, err error) {
+	source, err := r.readFile(name)
+	if err != nil {
+		return nil, err
+	}
+	file, err := Parse(name, string(source))
+	if err != nil {
+		return nil, err
+	}
+	including = append(including, name)
+	current := prefix
+	for _, line := range file.Lines {
+		switch line.Kind {
+		case PrefixLine:
+			current = joinPrefix(prefix, line.Prefix)
+		case IncludeLine:
+			include := r.join(r.dir(name), line.Include)
+			if slices.Contains(including, include) {
+				return nil, &Error{line.Pos, fmt.Errorf("include cycle through %q", include)}
+			}
+			included, err := r.read(include, joinPrefix(current, line.Prefix), including)
+			if err != nil {
+				var fileErr *Error
+				if errors.As(err, &fileErr) {
+					return nil, err
+				}
+				return nil, &Error{line.Pos, err}
+			}
+			routes = append(routes, included...)
+		case RouteLine:
+			route, err := joinRoute(current, line.Route)
+			if err != nil {
+				return nil, &Error{line.RoutePos, err}
+			}
+			routes = append(routes, &Route{
+				Pos:      line.RoutePos,
+				Method:   line.Method,
+				Route:    route,
+				Value:    line.Value,
+				prefixed: len(route) - len(line.Route),
+			})
+		}
+	}
+	return routes, nil
+}
+
+// joinPrefix joins the prefixes
+func joinPrefix(parent, prefix string) string {
+	if prefix == "" || prefix == "/" {
+		return parent
+	}
+	return strings.TrimRight(parent, "/") + prefix
+}
+
+// joinRoute puts the route under the prefix
+func joinRoute(prefix, route string) (string, error) {
+	switch {
+	case prefix == "":
+		return route, nil
+	case !strings.HasPrefix(route, "/"):
+		return "", fmt.Errorf("route %q with a host can't have the prefix %q", route, prefix)
+	case route == "/":
+		return prefix, nil
+	}
+	return strings.TrimRight(prefix, "/") + route, nil
+}
+
+// Load the routes into the tree. Routes with a method are inserted for that
+// method. Every route that can't be inserted is reported with its position.
+func Load(tree *enroute.Tree[string], routes []*Route) error {
+	var errs []error
+	for _, route := range routes {
+		var err error
+		if route.Method != "" {
+			err = tree.InsertMethod(route.Method, route.Route, route.Value)
+		} else {
+			err = tree.Insert(route.Route, route.Value)
+		}
+		if err != nil {
+			errs = append(errs, &Error{route.errorPos(err), err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// errorPos is the position of the error within the route. Errors in the
+// prefix are reported at the start of the route.
+func (r *Route) errorPos(err error) Position {
+	pos := r.Pos
+	var parseErr *parser.Error
+	if !errors.As(err, &parseErr) {
+		return pos
+	}
+	route := norm.NFC.String(r.Route)
+	// The tree parses the route without the trailing slashes of its path
+	offset := parseErr.Offset
+	if end, trimmed := trailingSlashes(route); offset >= end {
+		offset += trimmed
+	}
+	if offset = min(offset, len(route)); offset > r.prefixed {
+		pos.Column += utf8.RuneCountInString(route[r.prefixed:offset])
+	}
+	return pos
+}
+
+// trailingSlashes returns where the trailing slashes of the route's path start
+// and how many there are, not counting the slash of the root path
+func trailingSlashes(route string) (end, n int) {
+	path := route
+	depth := 0
+loop:
+	for i := 0; i < len(route); i++ {
+		switch route[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '?':
+			if depth == 0 {
+				path = route[:i]
+				break loop
+			}
+		}
+	}
+	trimmed := strings.TrimRight(path, "/")
+	if trimmed == "" {
+		trimmed = "/"
+	}
+	return len(trimmed), len(path) - len(trimmed)
+}
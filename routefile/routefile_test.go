@@ -0,0 +1,219 @@
+package routefile_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/matryer/is"
+	"github.com/matthewmueller/diff"
+	"github.com/matthewmueller/enroute"
+	"github.com/matthewmueller/enroute/routefile"
+)
+
+func TestParse(t *testing.T) {
+	is := is.New(t)
+	file, err := routefile.Parse("routes", `
+# Users
+GET  /users/{id}  users.show  # Show a user
+	POST /users users.create
+/about
+
+include admin.routes /admin
+prefix /api  # API
+prefix
+/a#b c#d #e
+`)
+	is.NoErr(err)
+	is.Equal(len(file.Lines), 10)
+	lines := make([]string, len(file.Lines))
+	for i, line := range file.Lines {
+		lines[i] = fmt.Sprintf("%s kind=%d method=%q route=%q value=%q include=%q prefix=%q comment=%q", line.Pos, line.Kind, line.Method, line.Route, line.Value, line.Include, line.Prefix, line.Comment)
+	}
+	diff.TestString(t, `
+routes:1:1 kind=0 method="" route="" value="" include="" prefix="" comment=""
+routes:2:1 kind=0 method="" route="" value="" include="" prefix="" comment="# Users"
+routes:3:1 kind=1 method="GET" route="/users/{id}" value="users.show" include="" prefix="" comment="# Show a user"
+routes:4:2 kind=1 method="POST" route="/users" value="users.create" include="" prefix="" comment=""
+routes:5:1 kind=1 method="" route="/about" value="" include="" prefix="" comment=""
+routes:6:1 kind=0 method="" route="" value="" include="" prefix="" comment=""
+routes:7:1 kind=2 method="" route="" value="" include="admin.routes" prefix="/admin" comment=""
+routes:8:1 kind=3 method="" route="" value="" include="" prefix="/api" comment="# API"
+routes:9:1 kind=3 method="" route="" value="" include="" prefix="" comment=""
+routes:10:1 kind=1 method="" route="/a#b" value="c#d" include="" prefix="" comment="#e"
+`[1:], strings.Join(lines, "\n")+"\n")
+	is.Equal(file.Lines[2].RoutePos.String(), "routes:3:6")
+}
+
+func TestParseError(t *testing.T) {
+	is := is.New(t)
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{"GET", "routes:1:4: missing route after GET"},
+		{"/users\n  DELETE  # Delete", "routes:2:9: missing route after DELETE"},
+		{"/users users.index extra", `routes:1:20: unexpected "extra"`},
+		{"\n\n/日本 jp extra", `routes:3:8: unexpected "extra"`},
+		{"include", "routes:1:8: missing file after include"},
+		{"include a.routes admin", `routes:1:18: prefix "admin" must start with a slash`},
+		{"include a.routes /admin more", `routes:1:25: unexpected "more"`},
+		{"prefix api", `routes:1:8: prefix "api" must start with a slash`},
+		{"prefix /api /v1", `routes:1:13: unexpected "/v1"`},
+	}
+	for _, test := range tests {
+		_, err := routefile.Parse("routes", test.source)
+		is.True(err != nil)
+		is.Equal(err.Error(), test.err)
+		var fileErr *routefile.Error
+		is.True(errors.As(err, &fileErr))
+	}
+}
+
+var fsys = fstest.MapFS{
+	"routes": {Data: []byte(`
+/ home
+include admin/admin.routes /admin
+prefix /api/v1
+GET /users api.users
+include admin/admin.routes /admin
+prefix
+/about about
+`)},
+	"admin/admin.routes": {Data: []byte(`
+/ admin.index
+DELETE /users/{id} admin.users.delete
+include more.routes
+`)},
+	"admin/more.routes": {Data: []byte(`
+/settings admin.settings
+`)},
+}
+
+func describe(routes []*routefile.Route) string {
+	s := new(strings.Builder)
+	for _, route := range routes {
+		fmt.Fprintf(s, "%s %s %s %s\n", route.Pos, route.Method, route.Route, route.Value)
+	}
+	return s.String()
+}
+
+func TestReadFS(t *testing.T) {
+	is := is.New(t)
+	routes, err := routefile.ReadFS(fsys, "routes")
+	is.NoErr(err)
+	diff.TestString(t, `
+routes:2:1  / home
+admin/admin.routes:2:1  /admin admin.index
+admin/admin.routes:3:8 DELETE /admin/users/{id} admin.users.delete
+admin/more.routes:2:1  /admin/settings admin.settings
+routes:5:5 GET /api/v1/users api.users
+admin/admin.routes:2:1  /api/v1/admin admin.index
+admin/admin.routes:3:8 DELETE /api/v1/admin/users/{id} admin.users.delete
+admin/more.routes:2:1  /api/v1/admin/settings admin.settings
+routes:8:1  /about about
+`[1:], describe(routes))
+}
+
+func TestReadError(t *testing.T) {
+	is := is.New(t)
+	fsys := fstest.MapFS{
+		"routes":   {Data: []byte("/ home\ninclude a.routes\n")},
+		"a.routes": {Data: []byte("/a a\ninclude routes /b\n")},
+		"missing":  {Data: []byte("include nope.routes\n")},
+		"host":     {Data: []byte("prefix /admin\nadmin.example.com/ admin\n")},
+		"broken":   {Data: []byte("include bad.routes\n")},
+		"bad.routes": {Data: []byte(`
+/ index
+GET
+`)},
+	}
+	_, err := routefile.ReadFS(fsys, "routes")
+	is.Equal(err.Error(), `a.routes:2:1: include cycle through "routes"`)
+	_, err = routefile.ReadFS(fsys, "missing")
+	is.Equal(err.Error(), "missing:1:1: open nope.routes: file does not exist")
+	_, err = routefile.ReadFS(fsys, "host")
+	is.Equal(err.Error(), `host:2:1: route "admin.example.com/" with a host can't have the prefix "/admin"`)
+	_, err = routefile.ReadFS(fsys, "broken")
+	is.Equal(err.Error(), "bad.routes:3:4: missing route after GET")
+	_, err = routefile.ReadFS(fsys, "nope")
+	is.Equal(err.Error(), "open nope: file does not exist")
+}
+
+func TestReadFile(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	is.NoErr(os.MkdirAll(filepath.Join(dir, "admin"), 0755))
+	for name, file := range fsys {
+		is.NoErr(os.WriteFile(filepath.Join(dir, name), file.Data, 0644))
+	}
+	routes, err := routefile.ReadFile(filepath.Join(dir, "routes"))
+	is.NoErr(err)
+	is.Equal(len(routes), 9)
+	is.Equal(routes[1].Pos.File, filepath.Join(dir, "admin", "admin.routes"))
+	is.Equal(routes[1].Route, "/admin")
+}
+
+func TestLoad(t *testing.T) {
+	is := is.New(t)
+	routes, err := routefile.ReadFS(fsys, "routes")
+	is.NoErr(err)
+	tree := enroute.New()
+	is.NoErr(routefile.Load(tree, routes))
+	match, err := tree.MatchMethod("DELETE", "/api/v1/admin/users/10")
+	is.NoErr(err)
+	is.Equal(match.Value, "admin.users.delete")
+	is.Equal(match.String(), "/api/v1/admin/users/{id} id=10")
+	match, err = tree.Match("/about")
+	is.NoErr(err)
+	is.Equal(match.Value, "about")
+}
+
+func TestLoadError(t *testing.T) {
+	is := is.New(t)
+	file, err := routefile.Parse("routes", `
+/users/{id} users.show
+/users/{name} users.name
+  GET /Users users.index
+/posts/{id} posts.show
+`)
+	is.NoErr(err)
+	var routes []*routefile.Route
+	for _, line := range file.Lines {
+		if line.Kind == routefile.RouteLine {
+			routes = append(routes, &routefile.Route{Pos: line.RoutePos, Method: line.Method, Route: line.Route, Value: line.Value})
+		}
+	}
+	tree := enroute.New()
+	err = routefile.Load(tree, routes)
+	diff.TestString(t, err.Error(), `
+routes:3:1: route "/users/{name}" is ambiguous with "/users/{id}"
+routes:4:8: unexpected character 'U' in path`[1:])
+	is.True(errors.Is(err, enroute.ErrDuplicate))
+	// The other routes are still loaded
+	match, err := tree.Match("/posts/10")
+	is.NoErr(err)
+	is.Equal(match.Value, "posts.show")
+	// Errors in prefixed routes are reported within the route in the file
+	routes, err = routefile.ReadFS(fstest.MapFS{
+		"routes": {Data: []byte("prefix /api/v1\n  /users/{id users.show\n")},
+	}, "routes")
+	is.NoErr(err)
+	err = routefile.Load(enroute.New(), routes)
+	is.Equal(err.Error(), "routes:2:13: unclosed slot")
+	// Trailing slashes before the query don't shift the column
+	file, err = routefile.Parse("routes", "/search/?q={query&page={page} search\n")
+	is.NoErr(err)
+	routes = nil
+	for _, line := range file.Lines {
+		if line.Kind == routefile.RouteLine {
+			routes = append(routes, &routefile.Route{Pos: line.RoutePos, Method: line.Method, Route: line.Route, Value: line.Value})
+		}
+	}
+	err = routefile.Load(enroute.New(), routes)
+	is.Equal(err.Error(), "routes:1:18: invalid character '&' in slot")
+}
@@ -0,0 +1,174 @@
+// Package router is a net/http router built on top of enroute.
+package router
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/matthewmueller/enroute"
+)
+
+// New router
+func New(options ...enroute.Option) *Router {
+	return &Router{
+		tree: enroute.NewTree[http.Handler](options...),
+	}
+}
+
+// Router routes requests to handlers by method and route
+type Router struct {
+	tree *enroute.Tree[http.Handler]
+
+	// NotFound handles requests that don't match any route. Defaults to
+	// http.NotFound.
+	NotFound http.Handler
+
+	// MethodNotAllowed handles requests that match a route, but not the method.
+	// The Allow header is set before it's called. Defaults to responding with a
+	// 405 status code.
+	MethodNotAllowed http.Handler
+}
+
+var _ http.Handler = (*Router)(nil)
+
+// Mount the sub router's routes under the prefix (e.g. /orgs/{org}). Changes
+// to the sub router after mounting don't affect the router.
+func (r *Router) Mount(prefix string, sub *Router) error {
+	return r.tree.Mount(prefix, sub.tree)
+}
+
+// Handle registers a handler for the method and route
+func (r *Router) Handle(method string, route string, handler http.Handler) error {
+	return r.tree.InsertMethod(method, route, handler)
+}
+
+// HandleFunc registers a handler function for the method and route
+func (r *Router) HandleFunc(method string, route string, fn http.HandlerFunc) error {
+	return r.Handle(method, route, fn)
+}
+
+// Get registers a GET handler. HEAD requests are also handled by GET handlers.
+func (r *Router) Get(route string, handler http.Handler) error {
+	return r.Handle(http.MethodGet, route, handler)
+}
+
+// Post registers a POST handler
+func (r *Router) Post(route string, handler http.Handler) error {
+	return r.Handle(http.MethodPost, route, handler)
+}
+
+// Put registers a PUT handler
+func (r *Router) Put(route string, handler http.Handler) error {
+	return r.Handle(http.MethodPut, route, handler)
+}
+
+// Patch registers a PATCH handler
+func (r *Router) Patch(route string, handler http.Handler) error {
+	return r.Handle(http.MethodPatch, route, handler)
+}
+
+// Delete registers a DELETE handler
+func (r *Router) Delete(route string, handler http.Handler) error {
+	return r.Handle(http.MethodDelete, route, handler)
+}
+
+// ServeHTTP routes the request to the matching handler
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// Match the escaped path, so encoded slashes don't separate segments
+	path := req.URL.EscapedPath()
+	if host := hostname(req.Host); strings.Contains(host, ".") {
+		path = host + path
+	}
+	// Pass the query along for routes like /search?q={query}
+	if req.URL.RawQuery != "" {
+		path += "?" + req.URL.RawQuery
+	}
+	match, err := r.tree.MatchMethod(req.Method, path)
+	if err != nil {
+		var notAllowed *enroute.ErrMethodNotAllowed
+		if !errors.As(err, &notAllowed) {
+			r.notFound(w, req)
+			return
+		}
+		switch {
+		case req.Method == http.MethodHead && slices.Contains(notAllowed.Allowed, http.MethodGet):
+			// Fallback to the GET handler
+			match, err = r.tree.MatchMethod(http.MethodGet, path)
+			if err != nil {
+				r.notFound(w, req)
+				return
+			}
+		case req.Method == http.MethodOptions:
+			w.Header().Set("Allow", allow(notAllowed.Allowed))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		default:
+			w.Header().Set("Allow", allow(notAllowed.Allowed))
+			r.methodNotAllowed(w, req)
+			return
+		}
+	}
+	if match.Redirect != "" {
+		r.redirect(w, req, match.Redirect)
+		return
+	}
+	for _, slot := range match.Slots {
+		req.SetPathValue(slot.Key, slot.Value)
+	}
+	match.Value.ServeHTTP(w, req)
+}
+
+// redirect to the path in the route's casing, keeping the query string. Other
+// methods than GET and HEAD are redirected with a 308 to keep the method and
+// body.
+func (r *Router) redirect(w http.ResponseWriter, req *http.Request, path string) {
+	if req.URL.RawQuery != "" {
+		path += "?" + req.URL.RawQuery
+	}
+	code := http.StatusMovedPermanently
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		code = http.StatusPermanentRedirect
+	}
+	http.Redirect(w, req, path, code)
+}
+
+func (r *Router) notFound(w http.ResponseWriter, req *http.Request) {
+	if r.NotFound != nil {
+		r.NotFound.ServeHTTP(w, req)
+		return
+	}
+	http.NotFound(w, req)
+}
+
+func (r *Router) methodNotAllowed(w http.ResponseWriter, req *http.Request) {
+	if r.MethodNotAllowed != nil {
+		r.MethodNotAllowed.ServeHTTP(w, req)
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+// hostname returns the host without its port
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// allow returns the Allow header for the methods, including the methods that
+// are answered automatically
+func allow(methods []string) string {
+	methods = slices.Clone(methods)
+	if slices.Contains(methods, http.MethodGet) && !slices.Contains(methods, http.MethodHead) {
+		methods = append(methods, http.MethodHead)
+	}
+	if !slices.Contains(methods, http.MethodOptions) {
+		methods = append(methods, http.MethodOptions)
+	}
+	slices.Sort(methods)
+	return strings.Join(methods, ", ")
+}
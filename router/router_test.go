@@ -0,0 +1,213 @@
+package router_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/matthewmueller/enroute"
+	"github.com/matthewmueller/enroute/router"
+)
+
+func handler(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s id=%s path=%s", name, r.PathValue("id"), r.PathValue("path"))
+	}
+}
+
+func request(t testing.TB, h http.Handler, method, path string) (*http.Response, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	res := rec.Result()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, string(body)
+}
+
+func TestRouter(t *testing.T) {
+	is := is.New(t)
+	r := router.New()
+	is.NoErr(r.Get("/", handler("index")))
+	is.NoErr(r.Get("/users/{id}", handler("show")))
+	is.NoErr(r.Patch("/users/{id}", handler("update")))
+	is.NoErr(r.Delete("/users/{id}", handler("delete")))
+	is.NoErr(r.Post("/users", handler("create")))
+	is.NoErr(r.Put("/files/{path*}", handler("upload")))
+	is.NoErr(r.HandleFunc("PURGE", "/cache", handler("purge")))
+	res, body := request(t, r, "GET", "/")
+	is.Equal(res.StatusCode, 200)
+	is.Equal(body, "index id= path=")
+	res, body = request(t, r, "GET", "/users/10")
+	is.Equal(res.StatusCode, 200)
+	is.Equal(body, "show id=10 path=")
+	res, body = request(t, r, "PATCH", "/users/10/")
+	is.Equal(res.StatusCode, 200)
+	is.Equal(body, "update id=10 path=")
+	res, body = request(t, r, "DELETE", "/users/10")
+	is.Equal(res.StatusCode, 200)
+	is.Equal(body, "delete id=10 path=")
+	res, body = request(t, r, "POST", "/users")
+	is.Equal(res.StatusCode, 200)
+	is.Equal(body, "create id= path=")
+	res, body = request(t, r, "PUT", "/files/a/b/c.txt")
+	is.Equal(res.StatusCode, 200)
+	is.Equal(body, "upload id= path=a/b/c.txt")
+	res, body = request(t, r, "PURGE", "/cache")
+	is.Equal(res.StatusCode, 200)
+	is.Equal(body, "purge id= path=")
+	// Duplicate routes
+	is.True(r.Get("/users/{id}", handler("show")) != nil)
+}
+
+func TestNotFound(t *testing.T) {
+	is := is.New(t)
+	r := router.New()
+	is.NoErr(r.Get("/users/{id}", handler("show")))
+	res, body := request(t, r, "GET", "/posts")
+	is.Equal(res.StatusCode, 404)
+	is.Equal(body, "404 page not found\n")
+	r.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("custom not found " + r.URL.Path))
+	})
+	res, body = request(t, r, "GET", "/posts")
+	is.Equal(res.StatusCode, 404)
+	is.Equal(body, "custom not found /posts")
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	is := is.New(t)
+	r := router.New()
+	is.NoErr(r.Get("/users/{id}", handler("show")))
+	is.NoErr(r.Patch("/users/{id}", handler("update")))
+	res, body := request(t, r, "POST", "/users/10")
+	is.Equal(res.StatusCode, 405)
+	is.Equal(res.Header.Get("Allow"), "GET, HEAD, OPTIONS, PATCH")
+	is.Equal(body, "Method Not Allowed\n")
+	r.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte("custom " + w.Header().Get("Allow")))
+	})
+	res, body = request(t, r, "POST", "/users/10")
+	is.Equal(res.StatusCode, 405)
+	is.Equal(body, "custom GET, HEAD, OPTIONS, PATCH")
+}
+
+func TestHead(t *testing.T) {
+	is := is.New(t)
+	r := router.New()
+	is.NoErr(r.Get("/users/{id}", handler("show")))
+	is.NoErr(r.Post("/users", handler("create")))
+	res, _ := request(t, r, "HEAD", "/users/10")
+	is.Equal(res.StatusCode, 200)
+	res, _ = request(t, r, "HEAD", "/users")
+	is.Equal(res.StatusCode, 405)
+	is.Equal(res.Header.Get("Allow"), "OPTIONS, POST")
+	// Explicit HEAD handlers take priority
+	is.NoErr(r.Handle("HEAD", "/users/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	res, _ = request(t, r, "HEAD", "/users/10")
+	is.Equal(res.StatusCode, 204)
+}
+
+func TestOptions(t *testing.T) {
+	is := is.New(t)
+	r := router.New()
+	is.NoErr(r.Get("/users/{id}", handler("show")))
+	is.NoErr(r.Delete("/users/{id}", handler("delete")))
+	res, body := request(t, r, "OPTIONS", "/users/10")
+	is.Equal(res.StatusCode, 204)
+	is.Equal(res.Header.Get("Allow"), "DELETE, GET, HEAD, OPTIONS")
+	is.Equal(body, "")
+	res, _ = request(t, r, "OPTIONS", "/posts")
+	is.Equal(res.StatusCode, 404)
+	// Explicit OPTIONS handlers take priority
+	is.NoErr(r.HandleFunc("OPTIONS", "/users/{id}", handler("options")))
+	res, body = request(t, r, "OPTIONS", "/users/10")
+	is.Equal(res.StatusCode, 200)
+	is.Equal(body, "options id=10 path=")
+}
+
+func TestCaseRedirect(t *testing.T) {
+	is := is.New(t)
+	r := router.New(enroute.WithCase(enroute.CaseRedirect))
+	is.NoErr(r.Get("/API/Users/{id}", handler("show")))
+	is.NoErr(r.Post("/API/Users", handler("create")))
+	res, body := request(t, r, "GET", "/API/Users/10")
+	is.Equal(res.StatusCode, 200)
+	is.Equal(body, "show id=10 path=")
+	res, _ = request(t, r, "GET", "/api/users/10?page=2")
+	is.Equal(res.StatusCode, http.StatusMovedPermanently)
+	is.Equal(res.Header.Get("Location"), "/API/Users/10?page=2")
+	res, _ = request(t, r, "POST", "/api/users")
+	is.Equal(res.StatusCode, http.StatusPermanentRedirect)
+	is.Equal(res.Header.Get("Location"), "/API/Users")
+}
+
+func TestEncodedPath(t *testing.T) {
+	is := is.New(t)
+	r := router.New()
+	is.NoErr(r.Get("/users/{id}", handler("show")))
+	is.NoErr(r.Get("/café/{id}", handler("cafe")))
+	res, body := request(t, r, "GET", "/users/hello%20world")
+	is.Equal(res.StatusCode, 200)
+	is.Equal(body, "show id=hello world path=")
+	res, body = request(t, r, "GET", "/users/a%2Fb")
+	is.Equal(res.StatusCode, 200)
+	is.Equal(body, "show id=a/b path=")
+	res, body = request(t, r, "GET", "/caf%C3%A9/10")
+	is.Equal(res.StatusCode, 200)
+	is.Equal(body, "cafe id=10 path=")
+}
+
+func TestHost(t *testing.T) {
+	is := is.New(t)
+	r := router.New()
+	is.NoErr(r.Get("{tenant}.example.com/users/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "tenant=%s id=%s", r.PathValue("tenant"), r.PathValue("id"))
+	})))
+	is.NoErr(r.Get("/users/{id}", handler("show")))
+	req := httptest.NewRequest("GET", "http://acme.example.com:8080/users/10", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	is.Equal(rec.Code, 200)
+	is.Equal(rec.Body.String(), "tenant=acme id=10")
+	res, body := request(t, r, "GET", "/users/10")
+	is.Equal(res.StatusCode, 200)
+	is.Equal(body, "show id=10 path=")
+}
+
+func TestQuery(t *testing.T) {
+	is := is.New(t)
+	r := router.New()
+	is.NoErr(r.Get("/search?q={query}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "query=%s", r.PathValue("query"))
+	})))
+	is.NoErr(r.Get("/search", handler("search")))
+	res, body := request(t, r, "GET", "/search?q=hello%20world")
+	is.Equal(res.StatusCode, 200)
+	is.Equal(body, "query=hello world")
+	res, body = request(t, r, "GET", "/search?page=2")
+	is.Equal(res.StatusCode, 200)
+	is.Equal(body, "search id= path=")
+}
+
+func TestMount(t *testing.T) {
+	is := is.New(t)
+	repos := router.New()
+	is.NoErr(repos.Get("/{repo}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "org=%s repo=%s", r.PathValue("org"), r.PathValue("repo"))
+	})))
+	r := router.New()
+	is.NoErr(r.Mount("/orgs/{org}", repos))
+	res, body := request(t, r, "GET", "/orgs/acme/enroute")
+	is.Equal(res.StatusCode, 200)
+	is.Equal(body, "org=acme repo=enroute")
+}
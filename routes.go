@@ -0,0 +1,116 @@
+package enroute
+
+import (
+	"iter"
+	"slices"
+	"strings"
+
+	"github.com/matthewmueller/enroute/ast"
+)
+
+// RouteInfo describes a route that was inserted into the tree
+type RouteInfo[V any] struct {
+	Route      string       // Route as it was inserted (e.g. /users/{id?})
+	Value      V            // Value of the route, empty for routes with methods
+	Methods    map[string]V // Values by method, nil for routes without methods
+	Meta       *Meta        // Metadata about the route, nil if there isn't any
+	Group      *Group[V]    // Group the route was inserted through, if any
+	Precedence int
+	AST        *ast.Route // Parsed route before it was expanded
+}
+
+// Routes yields each route in the tree once, without its expansions, in the
+// order the tree is traversed
+func (t *Tree[V]) Routes() iter.Seq[*RouteInfo[V]] {
+	return func(yield func(*RouteInfo[V]) bool) {
+		for _, route := range t.routes() {
+			if !yield(route) {
+				return
+			}
+		}
+	}
+}
+
+// SortedRoutes yields each route in the tree once, ordered by match priority.
+// Routes with a host come first, then routes are compared section by section,
+// so exact paths come before typed, regexp, required, optional and wildcard
+// slots. Routes that only differ by their query are ordered by the number of
+// query parameters they require.
+func (t *Tree[V]) SortedRoutes() iter.Seq[*RouteInfo[V]] {
+	return func(yield func(*RouteInfo[V]) bool) {
+		routes := t.routes()
+		slices.SortStableFunc(routes, func(a, b *RouteInfo[V]) int {
+			return comparePriority(a.AST, b.AST)
+		})
+		for _, route := range routes {
+			if !yield(route) {
+				return
+			}
+		}
+	}
+}
+
+// routes collects the route of each node once
+func (t *Tree[V]) routes() (routes []*RouteInfo[V]) {
+	seen := map[string]bool{}
+	add := func(n *Node[V]) {
+		if n.route == nil || seen[n.Label] {
+			return
+		}
+		seen[n.Label] = true
+		r, err := t.parse(n.Label)
+		if err != nil {
+			// Labels are parsed before they're inserted
+			return
+		}
+		routes = append(routes, &RouteInfo[V]{
+			Route:      n.Label,
+			Value:      n.Value,
+			Methods:    n.ownMethods(),
+			Meta:       n.Meta,
+			Group:      n.group,
+			Precedence: n.precedence,
+			AST:        r,
+		})
+	}
+	t.Each(func(n *Node[V]) bool {
+		add(n)
+		// Shadowed routes are still in the tree, even if they never match
+		for _, shadowed := range n.shadowed {
+			add(shadowed)
+		}
+		return true
+	})
+	return routes
+}
+
+// comparePriority orders the routes by the order they're tried in
+func comparePriority(a, b *ast.Route) int {
+	switch {
+	case len(a.Host) > 0 && len(b.Host) == 0:
+		return -1
+	case len(a.Host) == 0 && len(b.Host) > 0:
+		return 1
+	}
+	if c := compareSections(a.Host, b.Host); c != 0 {
+		return c
+	}
+	if c := compareSections(a.Sections, b.Sections); c != 0 {
+		return c
+	}
+	return requiredQuery(b) - requiredQuery(a)
+}
+
+// compareSections orders sections with a higher priority first, then by their
+// text. Shorter sections come first when one starts with the other.
+func compareSections(a, b ast.Sections) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if pa, pb := a[i].Priority(), b[i].Priority(); pa != pb {
+			return pb - pa
+		}
+		if c := strings.Compare(a[i].String(), b[i].String()); c != 0 {
+			return c
+		}
+	}
+	return len(a) - len(b)
+}
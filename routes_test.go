@@ -0,0 +1,118 @@
+package enroute_test
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/matthewmueller/enroute"
+)
+
+func TestRoutes(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/users/{id?}", "users"))
+	is.NoErr(tree.Insert("/posts/{path*}", "posts"))
+	is.NoErr(tree.InsertMethod("GET", "/about", "about"))
+	is.NoErr(tree.InsertMethod("POST", "/about", "contact"))
+	is.NoErr(tree.Insert("/search?q={query}", "query"))
+	is.NoErr(tree.Insert("/search", "search"))
+	var routes []string
+	for route := range tree.Routes() {
+		routes = append(routes, route.Route)
+		switch route.Route {
+		case "/users/{id?}":
+			is.Equal(route.Value, "users")
+			is.Equal(route.AST.String(), "/users/{id?}")
+			is.Equal(route.Methods, nil)
+		case "/about":
+			is.Equal(route.Value, "")
+			is.Equal(route.Methods, map[string]string{"GET": "about", "POST": "contact"})
+		}
+	}
+	// Expanded routes are only yielded once
+	is.Equal(routes, []string{
+		"/users/{id?}",
+		"/posts/{path*}",
+		"/about",
+		"/search?q={query}",
+		"/search",
+	})
+	// Stop early
+	count := 0
+	for range tree.Routes() {
+		count++
+		break
+	}
+	is.Equal(count, 1)
+}
+
+func TestRoutesPrecedence(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/{id?}", "optional"))
+	is.NoErr(tree.Insert("/users", "users"))
+	for route := range tree.Routes() {
+		switch route.Route {
+		case "/{id?}":
+			is.Equal(route.Precedence, route.AST.Precedence())
+		}
+	}
+}
+
+func TestSortedRoutes(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.NoErr(tree.Insert("/{path*}", "wildcard"))
+	is.NoErr(tree.Insert("/users/{id?}", "optional"))
+	is.NoErr(tree.Insert("/users/{id:int}/edit", "typed"))
+	is.NoErr(tree.Insert("/users/{id}/edit", "required"))
+	is.NoErr(tree.Insert("/users/{id|[a-z]+}/edit", "regexp"))
+	is.NoErr(tree.Insert("/users/new", "new"))
+	is.NoErr(tree.Insert("/search", "search"))
+	is.NoErr(tree.Insert("/search?q={query}", "query"))
+	is.NoErr(tree.Insert("/about", "about"))
+	is.NoErr(tree.Insert("{tenant}.example.com/", "tenant"))
+	is.NoErr(tree.Insert("api.example.com/", "api"))
+	var routes []string
+	for route := range tree.SortedRoutes() {
+		routes = append(routes, route.Route)
+	}
+	is.Equal(routes, []string{
+		"api.example.com/",
+		"{tenant}.example.com/",
+		"/about",
+		"/search?q={query}",
+		"/search",
+		"/users/new",
+		"/users/{id:int}/edit",
+		"/users/{id|^[a-z]+$}/edit",
+		"/users/{id}/edit",
+		"/users/{id?}",
+		"/{path*}",
+	})
+}
+
+func TestRoutesGroup(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	admin := tree.Group("/admin").Tag("admin")
+	is.NoErr(admin.Insert("/users", "users"))
+	is.NoErr(tree.Insert("/", "index"))
+	tables := map[string][]string{}
+	for route := range tree.SortedRoutes() {
+		prefix := ""
+		if route.Group != nil {
+			prefix = route.Group.Prefix()
+		}
+		tables[prefix] = append(tables[prefix], route.Route)
+	}
+	is.Equal(tables, map[string][]string{
+		"":       {"/"},
+		"/admin": {"/admin/users"},
+	})
+	for route := range tree.Routes() {
+		if route.Route == "/admin/users" {
+			is.True(route.Meta.HasTag("admin"))
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package enroute
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/matthewmueller/enroute/ast"
+)
+
+// Suggest returns up to n routes that are closest to the path, ranked by an
+// edit distance over the path segments. Literal segments are compared
+// character by character and slots match any segment they accept, so
+// /user/42 suggests /users/{id}. This is useful for "did you mean"
+// suggestions on 404 pages. Paths with a host are compared with the routes of
+// each host that matches and the routes without a host.
+func (t *Tree[V]) Suggest(path string, n int) []string {
+	if (t.root == nil && t.hosts == nil) || n <= 0 {
+		return nil
+	}
+	path, _ = cutQuery(path)
+	host, path := splitHost(normalize(trimTrailingSlash(path)))
+	input := splitPath(path)
+	var trees []*Tree[V]
+	if host != "" {
+		for _, h := range t.matchHost(host) {
+			trees = append(trees, t.trees[h.node.Label])
+		}
+	}
+	best := map[string]int{}
+	var ranked []suggestion
+	visit := func(node *Node[V]) bool {
+		if node.route == nil {
+			return true
+		}
+		// Skip routes that can't beat the suggestions we already have
+		cutoff := -1
+		if len(ranked) >= n {
+			cutoff = ranked[n-1].distance
+		}
+		distance, ok := segmentDistance(input, splitSections(node.route.Sections), cutoff)
+		if !ok {
+			return true
+		}
+		if prev, ok := best[node.Label]; ok && prev <= distance {
+			return true
+		}
+		best[node.Label] = distance
+		ranked = slices.DeleteFunc(ranked, func(s suggestion) bool {
+			return s.label == node.Label
+		})
+		ranked = append(ranked, suggestion{node.Label, distance})
+		slices.SortFunc(ranked, func(a, b suggestion) int {
+			if a.distance != b.distance {
+				return a.distance - b.distance
+			}
+			return strings.Compare(a.label, b.label)
+		})
+		ranked = ranked[:min(n, len(ranked))]
+		return true
+	}
+	for _, tree := range append(trees, t) {
+		if tree.root != nil {
+			tree.each(tree.root, visit)
+		}
+	}
+	suggestions := make([]string, 0, len(ranked))
+	for _, s := range ranked {
+		suggestions = append(suggestions, s.label)
+	}
+	return suggestions
+}
+
+type suggestion struct {
+	label    string
+	distance int
+}
+
+// splitPath splits the path into its segments (e.g. /users/10 => users, 10)
+func splitPath(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// splitSections splits the sections into segments at each slash
+func splitSections(sections ast.Sections) (segments []ast.Sections) {
+	start := 0
+	for i, section := range sections {
+		if _, ok := section.(*ast.Slash); !ok {
+			continue
+		}
+		if i > start {
+			segments = append(segments, sections[start:i])
+		}
+		start = i + 1
+	}
+	if start < len(sections) {
+		segments = append(segments, sections[start:])
+	}
+	return segments
+}
+
+// segmentDistance is the edit distance between the input segments and the
+// route segments. Adding or removing a segment costs its length, while
+// replacing a segment costs the distance between the two, so the distance is
+// roughly the number of characters that would need to change. Slots count as
+// a single character and a trailing wildcard segment absorbs the rest of the
+// input. If cutoff isn't negative, it stops early and returns false once the
+// distance is known to be over the cutoff.
+func segmentDistance(input []string, route []ast.Sections, cutoff int) (int, bool) {
+	prev := make([]int, len(input)+1)
+	curr := make([]int, len(input)+1)
+	for i, value := range input {
+		prev[i+1] = prev[i] + segmentLen(value)
+	}
+	for _, segment := range route {
+		wildcard := isWildcard(segment)
+		weight := sectionsLen(segment)
+		curr[0] = prev[0] + weight
+		lowest := curr[0]
+		for i, value := range input {
+			curr[i+1] = min(
+				prev[i]+segmentCost(segment, value),
+				prev[i+1]+weight,
+				curr[i]+segmentLen(value),
+			)
+			if wildcard {
+				// Once the wildcard has matched, it absorbs the following segments
+				curr[i+1] = min(curr[i+1], curr[i])
+			}
+			lowest = min(lowest, curr[i+1])
+		}
+		if cutoff >= 0 && lowest > cutoff {
+			return 0, false
+		}
+		prev, curr = curr, prev
+	}
+	distance := prev[len(input)]
+	if cutoff >= 0 && distance > cutoff {
+		return 0, false
+	}
+	return distance, true
+}
+
+// segmentLen is the number of characters in the segment
+func segmentLen(value string) int {
+	return max(utf8.RuneCountInString(value), 1)
+}
+
+// sectionsLen is the number of characters in the literal sections, where
+// slots count as one character
+func sectionsLen(sections ast.Sections) (n int) {
+	for _, section := range sections {
+		if path, ok := section.(*ast.Path); ok {
+			n += utf8.RuneCountInString(path.Value)
+			continue
+		}
+		n++
+	}
+	return n
+}
+
+func isWildcard(segment ast.Sections) bool {
+	_, ok := segment[len(segment)-1].(*ast.WildcardSlot)
+	return ok
+}
+
+// segmentCost is the cost of replacing the input value with the route segment
+func segmentCost(segment ast.Sections, value string) int {
+	if segmentMatches(segment, value) {
+		return 0
+	}
+	// Compare literal segments by how many characters differ
+	literal := new(strings.Builder)
+	for _, section := range segment {
+		path, ok := section.(*ast.Path)
+		if !ok {
+			return sectionsLen(segment)
+		}
+		literal.WriteString(path.Value)
+	}
+	return levenshtein(strings.ToLower(literal.String()), strings.ToLower(value))
+}
+
+// segmentMatches is true if the route segment matches the whole value
+func segmentMatches(segment ast.Sections, value string) bool {
+	for _, section := range segment {
+		if _, ok := section.(*ast.WildcardSlot); ok {
+			return value != ""
+		}
+		index, _ := section.Match(value)
+		if index <= 0 {
+			return false
+		}
+		value = value[index:]
+	}
+	return value == ""
+}
+
+// levenshtein is the number of single character edits between a and b
+func levenshtein(a, b string) int {
+	r1, r2 := []rune(a), []rune(b)
+	prev := make([]int, len(r2)+1)
+	curr := make([]int, len(r2)+1)
+	for i := range prev {
+		prev[i] = i
+	}
+	for i := range r1 {
+		curr[0] = i + 1
+		for j := range r2 {
+			cost := 1
+			if r1[i] == r2[j] {
+				cost = 0
+			}
+			curr[j+1] = min(prev[j]+cost, prev[j+1]+1, curr[j]+1)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(r2)]
+}
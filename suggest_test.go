@@ -0,0 +1,66 @@
+package enroute_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/matthewmueller/enroute"
+)
+
+func TestSuggest(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	tree.MustInsert("/", "index")
+	tree.MustInsert("/users", "users/index")
+	tree.MustInsert("/users/{id}", "users/show")
+	tree.MustInsert("/users/{id}/edit", "users/edit")
+	tree.MustInsert("/posts/{id|[0-9]+}", "posts/show")
+	tree.MustInsert("/posts/{post_id}/comments/{id?}", "comments/show")
+	tree.MustInsert("/files/{path*}", "files/show")
+	is.Equal(tree.Suggest("/user/42", 1), []string{"/users/{id}"})
+	is.Equal(tree.Suggest("/user/42", 3), []string{"/users/{id}", "/users", "/files/{path*}"})
+	is.Equal(tree.Suggest("/users/42/edt", 1), []string{"/users/{id}/edit"})
+	is.Equal(tree.Suggest("/post/42", 1), []string{"/posts/{id|^[0-9]+$}"})
+	is.Equal(tree.Suggest("/posts/42/coments", 1), []string{"/posts/{post_id}/comments/{id?}"})
+	is.Equal(tree.Suggest("/file/a/b/c", 1), []string{"/files/{path*}"})
+	is.Equal(tree.Suggest("/USER", 1), []string{"/users"})
+	is.Equal(tree.Suggest("/", 1), []string{"/"})
+	is.Equal(tree.Suggest("/users/42", 0), nil)
+	is.Equal(len(tree.Suggest("/anything", 100)), 7)
+}
+
+func TestSuggestHost(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	tree.MustInsert("/about", "about")
+	tree.MustInsert("api.example.com/users", "api/users")
+	tree.MustInsert("{tenant}.example.com/posts", "tenant/posts")
+	is.Equal(tree.Suggest("api.example.com/usres", 1), []string{"api.example.com/users"})
+	is.Equal(tree.Suggest("acme.example.com/post", 1), []string{"{tenant}.example.com/posts"})
+	is.Equal(tree.Suggest("api.example.com/abut", 1), []string{"/about"})
+	// Routes of other hosts aren't suggested
+	is.Equal(tree.Suggest("/usres", 3), []string{"/about"})
+	is.Equal(tree.Suggest("other.com/usres", 3), []string{"/about"})
+}
+
+func TestSuggestEmpty(t *testing.T) {
+	is := is.New(t)
+	tree := enroute.New()
+	is.Equal(tree.Suggest("/users", 3), nil)
+}
+
+func BenchmarkSuggest(b *testing.B) {
+	tree := enroute.New()
+	// A few thousand routes, like a large app would have
+	for i := 0; i < 1000; i++ {
+		tree.MustInsert(fmt.Sprintf("/resource%d", i), "index")
+		tree.MustInsert(fmt.Sprintf("/resource%d/{id}", i), "show")
+		tree.MustInsert(fmt.Sprintf("/resource%d/{id}/edit", i), "edit")
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree.Suggest("/resourse500/42/edt", 3)
+	}
+}